@@ -0,0 +1,334 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composed"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/crossplane/function-template-go/input/v1beta1"
+	"github.com/crossplane/function-template-go/internal/slackchannel"
+)
+
+// Modes the collectors of the polls run in.
+const (
+	collectorModePerPoll = "PerPoll"
+	collectorModeShared  = "Shared"
+)
+
+// collectorLabel labels the polls sharing a collector with the namespace and
+// the name of the collector, so each poll can find the others.
+const collectorLabel = "poll.kndp.io/collector"
+
+// maxLabelValueLength is the longest value a label can have.
+const maxLabelValueLength = 63
+
+// collectorRef returns the value of the collector label of the polls sharing
+// the collector of the namespace. Values that don't fit a label are shortened
+// and end with a hash of the full value, so they stay distinct.
+func collectorRef(namespace, name string) string {
+	ref := namespace + "." + name
+	if len(ref) <= maxLabelValueLength {
+		return ref
+	}
+	sum := sha256.Sum256([]byte(ref))
+	tail := "-" + hex.EncodeToString(sum[:])[:10]
+	return strings.TrimRight(ref[:maxLabelValueLength-len(tail)], "-.") + tail
+}
+
+// sharedCollectorPath is the path the shared collector receives the Slack
+// interactions of all polls on.
+const sharedCollectorPath = "/events"
+
+// Management policies of the shared collector. The Objects of the poll
+// composing it keep the collector when they're deleted, so it survives the
+// poll handing it over to another one. Once no poll needs it, the poll that
+// composed it last releases it before dropping its Objects, which deletes it.
+var (
+	keepPolicies    = []string{"Observe", "Create", "Update", "LateInitialize"}
+	releasePolicies = []string{"*"}
+)
+
+// reopenWindow is how long organizers can reopen a round after its results
+// were posted or it was cancelled, unless the next round opens before.
+const reopenWindow = 24 * time.Hour
+
+// needsCollector checks if the poll needs a collector: while it accepts votes,
+// while its organizers can reopen the round it finished, or while voters can
+// still mark their share as paid or confirm the pickup of their order.
+func needsCollector(xr *resource.Composite, now int64) bool {
+	if slackchannel.AwaitsPayment(xr) || slackchannel.AwaitsPickup(xr) {
+		return true
+	}
+	switch slackchannel.GetPhase(xr) {
+	case slackchannel.PhasePending, slackchannel.PhaseOpen:
+		return true
+	case slackchannel.PhaseResultsPosted, slackchannel.PhaseCancelled:
+		organizers, _ := xr.Resource.GetStringArray("spec.organizers")
+		if len(organizers) == 0 {
+			return false
+		}
+		finished, _ := xr.Resource.GetInteger("status.phaseTimestamps.resultsPosted")
+		if slackchannel.GetPhase(xr) == slackchannel.PhaseCancelled {
+			finished, _ = xr.Resource.GetInteger("status.phaseTimestamps.cancelled")
+		}
+		deadline := finished + int64(reopenWindow/time.Second)
+		if next, err := xr.Resource.GetInteger("status.nextRunTime"); err == nil && next < deadline {
+			deadline = next
+		}
+		return now < deadline
+	default:
+		return false
+	}
+}
+
+// sharedCollectorLeader returns whether the poll composes the shared
+// collector, and whether any of the polls sharing it needs it. The oldest
+// poll needing the collector composes it, polls created at the same time are
+// ordered by name.
+func sharedCollectorLeader(xr *resource.Composite, polls []*unstructured.Unstructured, now int64) (leader, needed bool) {
+	leader = needsCollector(xr, now)
+	needed = leader
+	for _, u := range polls {
+		poll := &resource.Composite{Resource: &composite.Unstructured{Unstructured: *u}}
+		if poll.Resource.GetUID() == xr.Resource.GetUID() && poll.Resource.GetName() == xr.Resource.GetName() {
+			continue
+		}
+		if !needsCollector(poll, now) {
+			continue
+		}
+		needed = true
+		if olderPoll(poll, xr) {
+			leader = false
+		}
+	}
+	return leader, needed
+}
+
+// olderPoll checks if poll a was created before poll b.
+func olderPoll(a, b *resource.Composite) bool {
+	ta, tb := a.Resource.GetCreationTimestamp(), b.Resource.GetCreationTimestamp()
+	if !ta.Equal(&tb) {
+		return ta.Before(&tb)
+	}
+	return a.Resource.GetName() < b.Resource.GetName()
+}
+
+// Keys of the composed collector and notifier. They are the names the
+// resources had before they were derived from the poll, Crossplane tracks the
+// resources of existing polls by them. The Deployment of the collector is
+// keyed by the deployment name of the input.
+const (
+	collectorServiceKey resource.Name = "service-collector"
+	collectorIngressKey resource.Name = "ingress-collector"
+	notifierKey         resource.Name = "slack-notify-cronjob"
+)
+
+// collectorPolicies returns the management policies of the observed
+// collector of the poll, and whether the poll composes one. Objects without
+// policies have the default ones, which delete the collector.
+func collectorPolicies(input *v1beta1.Input, observed map[resource.Name]resource.ObservedComposed) ([]string, bool) {
+	o, ok := observed[resource.Name(input.DeploymentName)]
+	if !ok {
+		return nil, false
+	}
+	policies, err := o.Resource.GetStringArray("spec.managementPolicies")
+	if err != nil || len(policies) == 0 {
+		return releasePolicies, true
+	}
+	return policies, true
+}
+
+// collectorConfig describes a collector composed by the function.
+type collectorConfig struct {
+	// objectName is the name of the Object of the Deployment, the Objects of
+	// the Service and the Ingress add a suffix to it.
+	objectName string
+	// name is the name of the Deployment, the Service and the Ingress.
+	name      string
+	namespace string
+	// path is the path the collector receives the Slack interactions on.
+	path string
+	env  []interface{}
+	// policies are the management policies of the Objects, the default
+	// policies apply if nil.
+	policies []string
+}
+
+// composeCollector returns the Objects of the Deployment, the Service and the
+// Ingress of a collector.
+func composeCollector(input *v1beta1.Input, c collectorConfig) map[resource.Name]*resource.DesiredComposed {
+	deployment := composed.Unstructured{
+		Unstructured: unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "kubernetes.crossplane.io/v1alpha2",
+				"kind":       "Object",
+				"metadata": map[string]interface{}{
+					"name": c.objectName,
+				},
+				"spec": map[string]interface{}{
+					"forProvider": map[string]interface{}{
+						"manifest": map[string]interface{}{
+							"apiVersion": "apps/v1",
+							"kind":       "Deployment",
+							"metadata": map[string]interface{}{
+								"name":      c.name,
+								"namespace": c.namespace,
+							},
+							"spec": map[string]interface{}{
+								"replicas": 1,
+								"selector": map[string]interface{}{
+									"matchLabels": map[string]interface{}{
+										"app": c.name,
+									},
+								},
+								"template": map[string]interface{}{
+									"metadata": map[string]interface{}{
+										"labels": map[string]interface{}{
+											"app": c.name,
+										},
+									},
+									"spec": map[string]interface{}{
+										"serviceAccountName": input.ServiceAccountName,
+										"containers": []interface{}{
+											map[string]interface{}{
+												"name":  "poll-container",
+												"image": input.DeploymentImage,
+												"env": append([]interface{}{
+													map[string]interface{}{
+														"name":  "SLACK_COLLECTOR_PATH",
+														"value": c.path,
+													},
+												}, c.env...),
+												"envFrom": []interface{}{
+													map[string]interface{}{
+														"secretRef": map[string]interface{}{
+															"name": secretName,
+														},
+													},
+												},
+												"ports": []interface{}{
+													map[string]interface{}{
+														"containerPort": 3000,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
+				},
+			},
+		},
+	}
+
+	svc := composed.Unstructured{
+		Unstructured: unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "kubernetes.crossplane.io/v1alpha2",
+				"kind":       "Object",
+				"metadata": map[string]interface{}{
+					"name": c.objectName + "-service",
+				},
+				"spec": map[string]interface{}{
+					"forProvider": map[string]interface{}{
+						"manifest": map[string]interface{}{
+							"apiVersion": "v1",
+							"kind":       "Service",
+							"metadata": map[string]interface{}{
+								"name":      c.name,
+								"namespace": c.namespace,
+							},
+							"spec": map[string]interface{}{
+								"ports": []interface{}{
+									map[string]interface{}{
+										"name":       "http",
+										"port":       80,
+										"targetPort": 3000,
+									},
+								},
+								"selector": map[string]interface{}{
+									"app": c.name,
+								},
+							},
+						},
+					},
+					"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
+				},
+			},
+		},
+	}
+
+	ingress := composed.Unstructured{
+		Unstructured: unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "kubernetes.crossplane.io/v1alpha2",
+				"kind":       "Object",
+				"metadata": map[string]interface{}{
+					"name": c.objectName + "-ingress",
+				},
+				"spec": map[string]interface{}{
+					"forProvider": map[string]interface{}{
+						"manifest": map[string]interface{}{
+							"apiVersion": "networking.k8s.io/v1",
+							"kind":       "Ingress",
+							"metadata": map[string]interface{}{
+								"name":      c.name,
+								"namespace": c.namespace,
+							},
+							"spec": map[string]interface{}{
+								"ingressClassName": "ngrok",
+								"rules": []interface{}{
+									map[string]interface{}{
+										"host": ngrokDomainName,
+										"http": map[string]interface{}{
+											"paths": []interface{}{
+												map[string]interface{}{
+													"path":     c.path,
+													"pathType": "Prefix",
+													"backend": map[string]interface{}{
+														"service": map[string]interface{}{
+															"name": c.name,
+															"port": map[string]interface{}{
+																"number": 80,
+															},
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
+				},
+			},
+		},
+	}
+
+	desired := map[resource.Name]*resource.DesiredComposed{
+		resource.Name(input.DeploymentName): {Resource: &deployment},
+		collectorServiceKey:                 {Resource: &svc},
+		collectorIngressKey:                 {Resource: &ingress},
+	}
+	if c.policies != nil {
+		policies := make([]interface{}, 0, len(c.policies))
+		for _, p := range c.policies {
+			policies = append(policies, p)
+		}
+		for _, d := range desired {
+			_ = d.Resource.SetValue("spec.managementPolicies", policies)
+		}
+	}
+	return desired
+}
@@ -3,11 +3,18 @@ package main
 import (
 	"context"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/slack-go/slack"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/errors"
+
 	"github.com/crossplane/function-sdk-go/logging"
 	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
 	"github.com/crossplane/function-sdk-go/request"
@@ -22,6 +29,130 @@ import (
 type Function struct {
 	fnv1beta1.UnimplementedFunctionRunnerServiceServer
 	log logging.Logger
+
+	// slack is the Slack client of the function, tests replace it with a
+	// client of a fake Slack. A client for the token of the poll is used if
+	// nil.
+	slack slackchannel.Messenger
+}
+
+// Condition types the function sets on the poll.
+const (
+	typeSlackReachable xpv1.ConditionType = "SlackReachable"
+	typeResultsPosted  xpv1.ConditionType = "ResultsPosted"
+)
+
+// Reasons of the poll conditions.
+const (
+	reasonAvailable       xpv1.ConditionReason = "Available"
+	reasonSlackAPIError   xpv1.ConditionReason = "SlackAPIError"
+	reasonPosted          xpv1.ConditionReason = "Posted"
+	reasonPosting         xpv1.ConditionReason = "Posting"
+	reasonPostFailed      xpv1.ConditionReason = "PostFailed"
+	reasonRoundInProgress xpv1.ConditionReason = "RoundInProgress"
+	reasonCancelled       xpv1.ConditionReason = "Cancelled"
+)
+
+// condition returns a poll condition of the given type.
+func condition(t xpv1.ConditionType, status corev1.ConditionStatus, reason xpv1.ConditionReason, err error) xpv1.Condition {
+	c := xpv1.Condition{
+		Type:               t,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+	}
+	if err != nil {
+		c.Message = err.Error()
+	}
+	return c
+}
+
+// validateInput checks that the input has everything needed to compose the
+// collector and the notifier.
+func validateInput(input *v1beta1.Input) error {
+	missing := []string{}
+	if input.DeploymentName == "" {
+		missing = append(missing, "deploymentName")
+	}
+	if input.DeploymentImage == "" {
+		missing = append(missing, "deploymentImage")
+	}
+	if input.CronJobImage == "" {
+		missing = append(missing, "cronJobImage")
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("input is missing %s", strings.Join(missing, ", "))
+	}
+	switch input.CollectorMode {
+	case "", collectorModePerPoll, collectorModeShared:
+	default:
+		return errors.Errorf("input has unknown collectorMode %q", input.CollectorMode)
+	}
+	return nil
+}
+
+// defaultTimeZone is the time zone of the notification schedule when neither
+// the poll nor the input set one.
+const defaultTimeZone = "Europe/Chisinau"
+
+// timeZone returns the time zone of the notification schedule of the poll,
+// checked against the IANA database the API server validates CronJobs with.
+func timeZone(xr *resource.Composite, input *v1beta1.Input) (*time.Location, error) {
+	zone, _ := xr.Resource.GetString("spec.timeZone")
+	if zone == "" {
+		zone = input.TimeZone
+	}
+	if zone == "" {
+		zone = defaultTimeZone
+	}
+	// LoadLocation accepts "Local", which the API server rejects.
+	if zone == "Local" {
+		return nil, errors.Errorf("invalid time zone %q", zone)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid time zone %q", zone)
+	}
+	return loc, nil
+}
+
+// defaultNamespace is the namespace the notifier and the collector run in
+// when the input doesn't set one.
+const defaultNamespace = "default"
+
+// workloadNamespace returns the namespace the notifier and the collector run
+// in.
+func workloadNamespace(input *v1beta1.Input) string {
+	if input.Namespace != "" {
+		return input.Namespace
+	}
+	return defaultNamespace
+}
+
+// Longest names of the composed resources. Services are DNS labels, and the
+// CronJob controller adds 11 characters to the names of the Jobs it creates.
+const (
+	maxNameLength        = 63
+	maxCronJobNameLength = 52
+)
+
+// composedName returns the name of a resource composed for the poll. Names
+// carry the start of the UID of the poll, so polls of the same name composed
+// after one another, or by claims in different namespaces, don't collide.
+// The name of the poll is shortened to fit the limit.
+func composedName(xr *resource.Composite, suffix string, limit int) string {
+	name := xr.Resource.GetName()
+	tail := "-" + suffix
+	if uid := strings.ReplaceAll(string(xr.Resource.GetUID()), "-", ""); uid != "" {
+		tail = "-" + uid[:min(len(uid), 8)] + tail
+	}
+	if n := max(limit-len(tail), 0); len(name) > n {
+		name = strings.TrimRight(name[:n], "-.")
+	}
+	if len(name+tail) > limit {
+		return strings.Trim((name + tail)[:limit], "-.")
+	}
+	return name + tail
 }
 
 var (
@@ -31,210 +162,357 @@ var (
 	ngrokDomainName = os.Getenv("NGROK_DOMAIN_NAME")
 )
 
-// Check if the dueOrderTime is passed or all users have voted
+// pollChannel returns the ID of the Slack channel of the poll, falling back to
+// the channel of the function. Channel names are resolved once, the ID is
+// recorded in status.channel and reused until spec.channel changes.
+func pollChannel(api slackchannel.Messenger, xr *resource.Composite) (string, error) {
+	channel, _ := xr.Resource.GetString("spec.channel")
+	if channel == "" {
+		unstructured.RemoveNestedField(xr.Resource.Object, "status", "channel")
+		return channelID, nil
+	}
+	name, _ := xr.Resource.GetString("status.channel.name")
+	id, _ := xr.Resource.GetString("status.channel.id")
+	if name == channel && id != "" {
+		return id, nil
+	}
+	id, err := slackchannel.ResolveChannel(api, channel)
+	if err != nil {
+		return "", err
+	}
+	return id, xr.Resource.SetValue("status.channel", map[string]interface{}{"name": channel, "id": id})
+}
+
+// slackEnv returns the environment that points the notifier and the collector
+// to the Slack channel and credentials of the poll. Without them, they use
+// the ones of the Secret they load their environment from.
+func slackEnv(xr *resource.Composite, channel string, credentials *slackchannel.SecretKeyRef) []interface{} {
+	env := []interface{}{}
+	if c, _ := xr.Resource.GetString("spec.channel"); c != "" && channel != "" {
+		env = append(env, map[string]interface{}{
+			"name":  "SLACK_CHANEL_ID",
+			"value": channel,
+		})
+	}
+	if credentials != nil {
+		env = append(env, map[string]interface{}{
+			"name": "SLACK_API_TOKEN",
+			"valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{
+					"name": credentials.Name,
+					"key":  credentials.Key,
+				},
+			},
+		})
+	}
+	return env
+}
+
+// Check if the close time is passed or all users have voted
 func checkDueOrderTimeAndVoteCount(xr *resource.Composite, currentTimestamp int, users []string) bool {
-	dueOrderTime, _ := xr.Resource.GetInteger("spec.dueOrderTime")
+	closeTime, _ := xr.Resource.GetInteger("status.closeTime")
 	lastNotificationTime, _ := xr.Resource.GetInteger("status.lastNotificationTime")
-	voters, _ := xr.Resource.GetStringArray("spec.voters")
+	voters, _ := xr.Resource.GetValue("status.voters")
+	votes, _ := voters.([]interface{})
 	if users == nil {
 		users = []string{""}
 	}
 	if lastNotificationTime == 0 {
 		return false
 	}
-	timestampDue := int(lastNotificationTime) + int(dueOrderTime)
-	return currentTimestamp >= timestampDue || len(voters) == len(users)
+	return currentTimestamp >= int(closeTime) || len(votes) == len(users)
 
 }
 
 // RunFunction adds a Deployment and the new object template to the desired state.
 func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequest) (*fnv1beta1.RunFunctionResponse, error) {
 	f.log.Info("Running Function")
+	rsp := response.To(req, response.DefaultTTL)
+
 	input := &v1beta1.Input{}
 	if err := request.GetInput(req, input); err != nil {
-		f.log.Info("cannot get function input", "warning", err)
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get Function input from %T", req))
+		return rsp, nil
+	}
+	if err := validateInput(input); err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
+	}
+	desired, err := request.GetDesiredComposedResources(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get desired composed resources from %T", req))
+		return rsp, nil
+	}
+	xr, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get observed composite resource from %T", req))
+		return rsp, nil
+	}
+	loc, err := timeZone(xr, input)
+	if err != nil {
+		response.Fatal(rsp, err)
+		return rsp, nil
 	}
-	api := slack.New(token)
 	currentTimestamp := int(time.Now().Unix())
-	desired, _ := request.GetDesiredComposedResources(req)
+	namespace := workloadNamespace(input)
+	if restored, err := slackchannel.RestoreOpenedRound(xr); err != nil {
+		response.Warning(rsp, errors.Wrap(err, "cannot restore the opened round"))
+	} else if restored {
+		response.Normal(rsp, "Restored the round the notifier opened")
+	}
+	record, err := slackchannel.ApplyAction(xr, currentTimestamp)
+	switch {
+	case err != nil:
+		response.Warning(rsp, errors.Wrap(err, "cannot apply action"))
+	case record != nil && record.Error != "":
+		response.Warning(rsp, errors.Errorf("rejected action %q: %s", record.Action, record.Error))
+	case record != nil:
+		response.Normalf(rsp, "Applied action %q", record.Action)
+	}
+	if err := slackchannel.SetCloseTime(xr, loc); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot compute the close time"))
+		return rsp, nil
+	}
 
-	rsp := response.To(req, response.DefaultTTL)
+	// Crossplane fetches the PollCalendar and the Slack credentials Secret
+	// the poll references, and the polls sharing its collector, and runs the
+	// function again once they're available.
+	requirements := map[string]*fnv1beta1.ResourceSelector{}
+	shared := input.CollectorMode != collectorModePerPoll
+	sharedRef := collectorRef(namespace, input.DeploymentName)
+	if shared {
+		requirements["polls"] = &fnv1beta1.ResourceSelector{
+			ApiVersion: xr.Resource.GetAPIVersion(),
+			Kind:       xr.Resource.GetKind(),
+			Match: &fnv1beta1.ResourceSelector_MatchLabels{MatchLabels: &fnv1beta1.MatchLabels{
+				Labels: map[string]string{collectorLabel: sharedRef},
+			}},
+		}
+	}
+	if name := slackchannel.CalendarRequirement(xr); name != "" {
+		requirements["calendar"] = &fnv1beta1.ResourceSelector{
+			ApiVersion: "kndp.io/v1alpha1",
+			Kind:       "PollCalendar",
+			Match:      &fnv1beta1.ResourceSelector_MatchName{MatchName: name},
+		}
+	}
+	credentials := slackchannel.CredentialsRef(xr)
+	if credentials != nil {
+		requirements["credentials"] = &fnv1beta1.ResourceSelector{
+			ApiVersion: "v1",
+			Kind:       "Secret",
+			Match: &fnv1beta1.ResourceSelector_MatchLabels{MatchLabels: &fnv1beta1.MatchLabels{
+				Labels: slackchannel.CredentialsSelector(*credentials, namespace),
+			}},
+		}
+	}
+	extra := map[string][]resource.Extra{}
+	if len(requirements) > 0 {
+		rsp.Requirements = &fnv1beta1.Requirements{ExtraResources: requirements}
+		if extra, err = request.GetExtraResources(req); err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot get extra resources from %T", req))
+			return rsp, nil
+		}
+		// Crossplane discards the response of the runs it hasn't fetched the
+		// required resources for yet, nothing is sent to Slack until then or
+		// it would be sent again by the next run.
+		missing := []string{}
+		for name := range requirements {
+			if _, ok := extra[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			slices.Sort(missing)
+			response.Warning(rsp, errors.Errorf("waiting for the required %s", strings.Join(missing, ", ")))
+			return rsp, nil
+		}
+	}
+	var calendar *unstructured.Unstructured
+	if calendars := extra["calendar"]; len(calendars) > 0 {
+		calendar = calendars[0].Resource
+	}
+
+	slackToken := token
+	if credentials != nil {
+		resources := make([]*unstructured.Unstructured, 0, len(extra["credentials"]))
+		for _, secret := range extra["credentials"] {
+			resources = append(resources, secret.Resource)
+		}
+		if slackToken, err = slackchannel.SlackToken(*credentials, namespace, resources); err != nil {
+			response.Fatal(rsp, errors.Wrap(err, "cannot get the Slack credentials"))
+			return rsp, nil
+		}
+		// The shared collector answers the interactions of every poll with
+		// the token of the function.
+		if shared {
+			response.Warning(rsp, errors.New("the shared collector doesn't use the Slack credentials of the poll, set collectorMode to PerPoll to use them"))
+		}
+	}
+	api := f.slack
+	if api == nil {
+		api = slack.New(slackToken)
+	}
 
-	xr, _ := request.GetObservedCompositeResource(req)
-	users, err := slackchannel.ProcessSlackMembers(api, channelID, f.log)
+	var users []string
+	channel, err := pollChannel(api, xr)
 	if err != nil {
-		f.log.Info("cannot get conversation members", "warning", err)
+		err = errors.Wrap(err, "cannot resolve the Slack channel")
+	} else if users, err = slackchannel.ProcessSlackMembers(api, channel, f.log); err != nil {
+		err = errors.Wrap(err, "cannot get conversation members")
+	}
+	if err != nil {
+		response.Warning(rsp, err)
+		xr.Resource.SetConditions(condition(typeSlackReachable, corev1.ConditionFalse, reasonSlackAPIError, err))
+	} else {
+		xr.Resource.SetConditions(condition(typeSlackReachable, corev1.ConditionTrue, reasonAvailable, nil))
 	}
 	pollTitle, _ := xr.Resource.GetString("spec.title")
 	pollName, _ := xr.Resource.GetString("metadata.name")
 	schedule, _ := xr.Resource.GetString("spec.schedule")
 	question, _ := xr.Resource.GetString("spec.messages.question")
-	d, _ := xr.Resource.GetBool("status.done")
 	resultText, _ := xr.Resource.GetString("spec.messages.result")
 
-	if checkDueOrderTimeAndVoteCount(xr, currentTimestamp, users) {
-		if !d {
-			xr.Resource.SetBool("status.done", true)
-			slackchannel.SlackOrder(input, api, xr, f.log, resultText)
-		}
-		xr.Resource.SetManagedFields(nil)
-		response.SetDesiredCompositeResource(rsp, xr)
+	slackchannel.MigrateVoters(xr, f.log)
+	if err := slackchannel.SetNextRun(xr, loc, time.Unix(int64(currentTimestamp), 0), calendar); err != nil {
+		response.Warning(rsp, errors.Wrap(err, "cannot compute the next run"))
+	}
+	if err := slackchannel.TrackPayments(api, channel, xr, currentTimestamp, f.log); err != nil {
+		response.Warning(rsp, errors.Wrap(err, "cannot track payments"))
+	}
+	if err := slackchannel.TrackDelivery(api, channel, xr, currentTimestamp, f.log); err != nil {
+		response.Warning(rsp, errors.Wrap(err, "cannot track delivery"))
+	}
 
-	} else {
-		xr.Resource.SetManagedFields(nil)
-		response.SetDesiredCompositeResource(rsp, xr)
-
-		deployment := composed.Unstructured{
-			Unstructured: unstructured.Unstructured{
-				Object: map[string]interface{}{
-					"apiVersion": "kubernetes.crossplane.io/v1alpha2",
-					"kind":       "Object",
-					"metadata": map[string]interface{}{
-						"name": input.DeploymentName,
-					},
-					"spec": map[string]interface{}{
-						"forProvider": map[string]interface{}{
-							"manifest": map[string]interface{}{
-								"apiVersion": "apps/v1",
-								"kind":       "Deployment",
-								"metadata": map[string]interface{}{
-									"name":      input.DeploymentName,
-									"namespace": "default",
-								},
-								"spec": map[string]interface{}{
-									"replicas": 1,
-									"selector": map[string]interface{}{
-										"matchLabels": map[string]interface{}{
-											"app": "poll",
-										},
-									},
-									"template": map[string]interface{}{
-										"metadata": map[string]interface{}{
-											"labels": map[string]interface{}{
-												"app": "poll",
-											},
-										},
-										"spec": map[string]interface{}{
-											"serviceAccountName": input.ServiceAccountName,
-											"containers": []interface{}{
-												map[string]interface{}{
-													"name":  "poll-container",
-													"image": input.DeploymentImage,
-													"envFrom": []interface{}{
-														map[string]interface{}{
-															"secretRef": map[string]interface{}{
-																"name": secretName,
-															},
-														},
-													},
-													"ports": []interface{}{
-														map[string]interface{}{
-															"containerPort": 3000,
-														},
-													},
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-						"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
-					},
-				},
-			},
+	if err := slackchannel.TrackReminders(api, channel, xr, currentTimestamp, f.log); err != nil {
+		response.Warning(rsp, errors.Wrap(err, "cannot send reminders"))
+	}
+	if slackchannel.GetPhase(xr) == slackchannel.PhaseOpen && checkDueOrderTimeAndVoteCount(xr, currentTimestamp, users) {
+		if err := slackchannel.SetPhase(xr, slackchannel.PhaseClosing, currentTimestamp); err != nil {
+			response.Warning(rsp, errors.Wrap(err, "cannot close poll"))
 		}
-
-		svc := composed.Unstructured{
-			Unstructured: unstructured.Unstructured{
-				Object: map[string]interface{}{
-					"apiVersion": "kubernetes.crossplane.io/v1alpha2",
-					"kind":       "Object",
-					"metadata": map[string]interface{}{
-						"name": "service-collector",
-					},
-					"spec": map[string]interface{}{
-						"forProvider": map[string]interface{}{
-							"manifest": map[string]interface{}{
-								"apiVersion": "v1",
-								"kind":       "Service",
-								"metadata": map[string]interface{}{
-									"name":      "service-collector",
-									"namespace": "default",
-								},
-								"spec": map[string]interface{}{
-									"ports": []interface{}{
-										map[string]interface{}{
-											"name":       "http",
-											"port":       80,
-											"targetPort": 3000,
-										},
-									},
-									"selector": map[string]interface{}{
-										"app": "poll",
-									},
-								},
-							},
-						},
-						"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
-					},
-				},
-			},
+	}
+	// Votes are frozen once the poll is Closed, results are posted afterwards.
+	if slackchannel.GetPhase(xr) == slackchannel.PhaseClosing {
+		if err := slackchannel.SetPhase(xr, slackchannel.PhaseClosed, currentTimestamp); err != nil {
+			response.Warning(rsp, errors.Wrap(err, "cannot close poll"))
 		}
-
-		ingress := composed.Unstructured{
-			Unstructured: unstructured.Unstructured{
-				Object: map[string]interface{}{
-					"apiVersion": "kubernetes.crossplane.io/v1alpha2",
-					"kind":       "Object",
-					"metadata": map[string]interface{}{
-						"name": "ingress-collector",
-					},
-					"spec": map[string]interface{}{
-						"forProvider": map[string]interface{}{
-							"manifest": map[string]interface{}{
-								"apiVersion": "networking.k8s.io/v1",
-								"kind":       "Ingress",
-								"metadata": map[string]interface{}{
-									"name":      "collector",
-									"namespace": "default",
-								},
-								"spec": map[string]interface{}{
-									"ingressClassName": "ngrok",
-									"rules": []interface{}{
-										map[string]interface{}{
-											"host": ngrokDomainName,
-											"http": map[string]interface{}{
-												"paths": []interface{}{
-													map[string]interface{}{
-														"path":     "/events",
-														"pathType": "Prefix",
-														"backend": map[string]interface{}{
-															"service": map[string]interface{}{
-																"name": "service-collector",
-																"port": map[string]interface{}{
-																	"number": 80,
-																},
-															},
-														},
-													},
-												},
-											},
-										},
-									},
-								},
-							},
-						},
-						"providerConfigRef": map[string]interface{}{"name": input.ProviderConfigRef},
-					},
-				},
-			},
+	}
+	if slackchannel.GetPhase(xr) == slackchannel.PhaseClosed {
+		err := slackchannel.SlackOrder(input, api, channel, xr, f.log, resultText)
+		switch {
+		case err != nil:
+			response.Warning(rsp, err)
+			xr.Resource.SetConditions(condition(typeResultsPosted, corev1.ConditionFalse, reasonPostFailed, err))
+		case slackchannel.GetPhase(xr) == slackchannel.PhaseResultsPosted:
+			response.Normalf(rsp, "Posted the results of poll %q", pollTitle)
+		default:
+			// The results are posted once Crossplane recorded that they're
+			// due, see SlackOrder.
+			response.Normalf(rsp, "Posting the results of poll %q", pollTitle)
+			xr.Resource.SetConditions(condition(typeResultsPosted, corev1.ConditionFalse, reasonPosting, nil))
+		}
+	}
+	switch slackchannel.GetPhase(xr) {
+	case slackchannel.PhaseResultsPosted:
+		xr.Resource.SetConditions(condition(typeResultsPosted, corev1.ConditionTrue, reasonPosted, nil))
+	case slackchannel.PhaseCancelled:
+		xr.Resource.SetConditions(condition(typeResultsPosted, corev1.ConditionFalse, reasonCancelled, nil))
+	case slackchannel.PhaseClosed:
+		// The condition was set when posting the results.
+	default:
+		xr.Resource.SetConditions(condition(typeResultsPosted, corev1.ConditionFalse, reasonRoundInProgress, nil))
+	}
+	// The polls share one collector behind /events, unless every poll runs
+	// its own behind its own path, which the Slack app of the poll sends its
+	// interactions to.
+	collector := composedName(xr, input.DeploymentName, maxNameLength)
+	collectorPath := "/events/" + collector
+	if shared {
+		collectorPath = sharedCollectorPath
+	}
+	notifier := composedName(xr, "slack-notify", maxCronJobNameLength)
+	if ngrokDomainName != "" {
+		if err := xr.Resource.SetString("status.interactivityURL", "https://"+ngrokDomainName+collectorPath); err != nil {
+			response.Warning(rsp, errors.Wrap(err, "cannot set the interactivity URL"))
 		}
+	}
 
-		desired[resource.Name(deployment.GetName())] = &resource.DesiredComposed{Resource: &deployment}
-		desired[resource.Name(svc.GetName())] = &resource.DesiredComposed{Resource: &svc}
-		desired[resource.Name(ingress.GetName())] = &resource.DesiredComposed{Resource: &ingress}
+	// Only the status the function owns is returned, returning the whole
+	// poll would apply back the votes the collector recorded since it was
+	// observed.
+	observed, err := request.GetObservedCompositeResource(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get observed composite resource from %T", req))
+		return rsp, nil
+	}
+	dxr, err := request.GetDesiredCompositeResource(req)
+	if err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot get desired composite resource from %T", req))
+		return rsp, nil
+	}
+	if err := desiredStatus(xr, observed, dxr); err != nil {
+		response.Fatal(rsp, errors.Wrap(err, "cannot set the desired status"))
+		return rsp, nil
+	}
+	// The polls sharing a collector find each other by their label.
+	if shared {
+		labels := dxr.Resource.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[collectorLabel] = sharedRef
+		dxr.Resource.SetLabels(labels)
+	}
+	if err := response.SetDesiredCompositeResource(rsp, dxr); err != nil {
+		response.Fatal(rsp, errors.Wrapf(err, "cannot set desired composite resource in %T", rsp))
+		return rsp, nil
+	}
 
+	if !shared {
+		if needsCollector(xr, int64(currentTimestamp)) {
+			for name, d := range composeCollector(input, collectorConfig{
+				objectName: collector,
+				name:       collector,
+				namespace:  namespace,
+				path:       collectorPath,
+				env:        slackEnv(xr, channel, credentials),
+			}) {
+				desired[name] = d
+			}
+		}
+	} else {
+		// Only one poll composes the shared collector, Crossplane doesn't let
+		// several composites compose the same resources. Polls that composed
+		// it before switch its policies first, so dropping it only deletes it
+		// when no poll needs it anymore.
+		observed, err := request.GetObservedComposedResources(req)
+		if err != nil {
+			response.Fatal(rsp, errors.Wrapf(err, "cannot get observed composed resources from %T", req))
+			return rsp, nil
+		}
+		current, composed := collectorPolicies(input, observed)
+		polls := make([]*unstructured.Unstructured, 0, len(extra["polls"]))
+		for _, poll := range extra["polls"] {
+			polls = append(polls, poll.Resource)
+		}
+		leader, needed := sharedCollectorLeader(xr, polls, int64(currentTimestamp))
+		policies := releasePolicies
+		if needed {
+			policies = keepPolicies
+		}
+		compose := leader || composed && !slices.Equal(current, policies)
+		if compose {
+			for name, d := range composeCollector(input, collectorConfig{
+				objectName: collector,
+				name:       input.DeploymentName,
+				namespace:  namespace,
+				path:       collectorPath,
+				policies:   policies,
+			}) {
+				desired[name] = d
+			}
+		}
 	}
 
 	cronjob := composed.Unstructured{
@@ -243,7 +521,7 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 				"apiVersion": "kubernetes.crossplane.io/v1alpha2",
 				"kind":       "Object",
 				"metadata": map[string]interface{}{
-					"name": "slack-notify-cronjob",
+					"name": notifier,
 				},
 				"spec": map[string]interface{}{
 					"forProvider": map[string]interface{}{
@@ -251,12 +529,12 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 							"apiVersion": "batch/v1",
 							"kind":       "CronJob",
 							"metadata": map[string]interface{}{
-								"name":      "slack-notify-cronjob",
-								"namespace": "default",
+								"name":      notifier,
+								"namespace": namespace,
 							},
 							"spec": map[string]interface{}{
 								"schedule": schedule,
-								"timeZone": "Europe/Chisinau",
+								"timeZone": loc.String(),
 								"jobTemplate": map[string]interface{}{
 									"spec": map[string]interface{}{
 										"template": map[string]interface{}{
@@ -267,7 +545,11 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 													map[string]interface{}{
 														"name":  "poll-container",
 														"image": input.CronJobImage,
-														"env": []interface{}{
+														"env": append([]interface{}{
+															map[string]interface{}{
+																"name":  "POLL_TIME_ZONE",
+																"value": loc.String(),
+															},
 															map[string]interface{}{
 																"name":  "SLACK_NOTIFY_MESSAGE",
 																"value": question,
@@ -280,7 +562,7 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 																"name":  "POLL_TITLE",
 																"value": pollTitle,
 															},
-														},
+														}, slackEnv(xr, channel, credentials)...),
 														"envFrom": []interface{}{
 															map[string]interface{}{
 																"secretRef": map[string]interface{}{
@@ -302,10 +584,11 @@ func (f *Function) RunFunction(_ context.Context, req *fnv1beta1.RunFunctionRequ
 			},
 		},
 	}
-	desired[resource.Name(cronjob.GetName())] = &resource.DesiredComposed{Resource: &cronjob}
+	desired[notifierKey] = &resource.DesiredComposed{Resource: &cronjob}
 
 	if err := response.SetDesiredComposedResources(rsp, desired); err != nil {
-		return rsp, err
+		response.Fatal(rsp, errors.Wrapf(err, "cannot set desired composed resources in %T", rsp))
+		return rsp, nil
 	}
 	return rsp, nil
 }
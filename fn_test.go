@@ -2,28 +2,53 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	jsonpatch "github.com/evanphx/json-patch/v5"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	fnv1beta1 "github.com/crossplane/function-sdk-go/proto/v1beta1"
 	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
 	"github.com/crossplane/function-sdk-go/response"
+	"github.com/crossplane/function-template-go/internal/slackchannel"
+	"github.com/crossplane/function-template-go/internal/slacktest"
 )
 
 func TestRunFunction(t *testing.T) {
-
+	input := resource.MustStructJSON(`{
+		"apiVersion": "template.fn.crossplane.io/v1beta1",
+		"kind": "Input",
+		"providerConfigRef": "default",
+		"deploymentName": "slack-collector",
+		"deploymentImage": "slack-collector:latest",
+		"serviceAccountName": "slack-collector",
+		"cronJobImage": "slack-notify:latest"
+	}`)
+	// The polls sharing the collector, as Crossplane fetches them.
+	polls := map[string]*fnv1beta1.Resources{"polls": {}}
 	type args struct {
-		ctx context.Context
-		req *fnv1beta1.RunFunctionRequest
+		req  *fnv1beta1.RunFunctionRequest
+		fail map[string]string
 	}
 	type want struct {
-		rsp *fnv1beta1.RunFunctionResponse
-		err error
+		results    []*fnv1beta1.Result
+		conditions []xpv1.Condition
 	}
 
 	cases := map[string]struct {
@@ -31,232 +56,244 @@ func TestRunFunction(t *testing.T) {
 		args   args
 		want   want
 	}{
-		"Desired resources when poll is in progress": {
-			reason: "The Function should return a fatal result if no input was specified",
+		"IncompleteInput": {
+			reason: "The Function should return a fatal result if the input misses required fields",
 			args: args{
 				req: &fnv1beta1.RunFunctionRequest{
 					Input: resource.MustStructJSON(`{
-						"slackAPIToken": "SLACKTOKEN",
-						"slackNotifyMessage": "Order something?",
-						"slackChanelID": "SLACKCHANNEL",
-						"providerConfigRef": "",
-						"deploymentName": "",
-						"deploymentServiceAccount": "",
-						"deploymentImage": "",
-						"configMap": ""
+						"apiVersion": "template.fn.crossplane.io/v1beta1",
+						"kind": "Input"
 					}`),
+				},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_FATAL,
+					Message:  "input is missing deploymentName, deploymentImage, cronJobImage",
+				}},
+			},
+		},
+		"InvalidTimeZone": {
+			reason: "The Function should return a fatal result if the poll time zone isn't in the IANA database",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
 					Observed: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(`
-							{
+							Resource: resource.MustStructJSON(`{
 								"apiVersion": "kndp.io/v1alpha1",
-								"kind": "Meal",
-								"metadata": {
-								  "name": "meal",
-								  "annotations": {
-									"poll.fn.kndp.io/last-sent-time": ""
-								  }
-								},
-								"spec": {
-								  "deliveryTime": "",
-								  "dueOrderTime": "",
-								  "dueTakeTime": "",
-								  "employeeRefs": [],
-								  "status": ""
-								}
-							  }
-
-							`),
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "timeZone": "Europe/Atlantis"}
+							}`),
 						},
 					},
-					Desired: &fnv1beta1.State{
-						Resources: map[string]*fnv1beta1.Resource{},
+				},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_FATAL,
+					Message:  `invalid time zone "Europe/Atlantis": unknown time zone Europe/Atlantis`,
+				}},
+			},
+		},
+		"WaitingForCredentials": {
+			reason: "The Function should not use Slack until Crossplane fetched the Slack credentials of the poll",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "kndp.io/v1alpha1",
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "slackCredentialsSecretRef": {"name": "slack"}}
+							}`),
+						},
 					},
+					ExtraResources: polls,
 				},
 			},
 			want: want{
-				rsp: &fnv1beta1.RunFunctionResponse{
-					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
-					Desired: &fnv1beta1.State{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+					Message:  "waiting for the required credentials",
+				}},
+			},
+		},
+		"CredentialsMissing": {
+			reason: "The Function should return a fatal result if the Slack credentials Secret of the poll doesn't exist",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(`
-							{
+							Resource: resource.MustStructJSON(`{
 								"apiVersion": "kndp.io/v1alpha1",
-								"kind": "Meal",
-								"metadata": {
-								  "annotations": {
-									"poll.fn.kndp.io/last-sent-time": ""
-								  },
-								  "name": "meal"
-								},
-								"spec": {
-								  "deliveryTime": "",
-								  "dueOrderTime": "",
-								  "dueTakeTime": "",
-								  "employeeRefs": [],
-								  "status": "done"
-								},
-								"status": {
-								  "conditions": [
-									{
-									  "lastTransitionTime": null,
-									  "reason": "",
-									  "status": "True",
-									  "type": "Synced"
-									}
-								  ]
-								}
-							  }
-							`),
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "slackCredentialsSecretRef": {"name": "slack"}}
+							}`),
 						},
 					},
+					ExtraResources: map[string]*fnv1beta1.Resources{"polls": {}, "credentials": {}},
 				},
 			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_FATAL,
+					Message:  "cannot get the Slack credentials: cannot find secret default/slack labelled poll.kndp.io/slack-credentials=slack and poll.kndp.io/slack-credentials-namespace=default",
+				}},
+			},
 		},
-		"Desired resources when dueOrderTime is over or all users have voted": {
-			reason: "The Function should return Object if dueOrderTime is over or all users have voted",
+		"UnknownChannel": {
+			reason: "The Function should return a warning and mark Slack as unreachable if the channel of the poll doesn't exist",
 			args: args{
 				req: &fnv1beta1.RunFunctionRequest{
-					Input: resource.MustStructJSON(`{
-						"slackAPIToken": "SLACKTOKEN",
-						"slackNotifyMessage": "Order something?",
-						"slackChanelID": "SLACKCHANNEL",
-						"providerConfigRef": "",
-						"deploymentName": "",
-						"deploymentServiceAccount": "",
-						"deploymentImage": "",
-						"configMap": ""
-					}`),
+					Input: input,
 					Observed: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(`
-							{
+							Resource: resource.MustStructJSON(`{
 								"apiVersion": "kndp.io/v1alpha1",
-								"kind": "Meal",
-								"metadata": {
-								  "name": "meal",
-								  "annotations": {
-									"poll.fn.kndp.io/last-sent-time": "100000000000000"
-								  }
-								},
-								"spec": {
-								  "deliveryTime": "",
-								  "dueOrderTime": "100000000000000",
-								  "dueTakeTime": "",
-								  "employeeRefs": [],
-								  "status": ""
-								}
-							  }
-
-							`),
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "channel": "#lunch"}
+							}`),
 						},
 					},
-					Desired: &fnv1beta1.State{
-						Resources: map[string]*fnv1beta1.Resource{},
+					ExtraResources: polls,
+				},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+					Message:  `cannot resolve the Slack channel: cannot find channel "#lunch"`,
+				}},
+				conditions: []xpv1.Condition{
+					{Type: typeSlackReachable, Status: corev1.ConditionFalse, Reason: reasonSlackAPIError, Message: `cannot resolve the Slack channel: cannot find channel "#lunch"`},
+					{Type: typeResultsPosted, Status: corev1.ConditionFalse, Reason: reasonRoundInProgress},
+				},
+			},
+		},
+		"SlackUnreachable": {
+			reason: "The Function should return a warning and mark Slack as unreachable if the Slack API fails",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "kndp.io/v1alpha1",
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5"}
+							}`),
+						},
 					},
+					ExtraResources: polls,
 				},
+				fail: map[string]string{"conversations.members": "invalid_auth"},
 			},
 			want: want{
-				rsp: &fnv1beta1.RunFunctionResponse{
-					Meta: &fnv1beta1.ResponseMeta{Ttl: durationpb.New(response.DefaultTTL)},
-					Desired: &fnv1beta1.State{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+					Message:  "cannot get conversation members: invalid_auth",
+				}},
+				conditions: []xpv1.Condition{
+					{Type: typeSlackReachable, Status: corev1.ConditionFalse, Reason: reasonSlackAPIError, Message: "cannot get conversation members: invalid_auth"},
+					{Type: typeResultsPosted, Status: corev1.ConditionFalse, Reason: reasonRoundInProgress},
+				},
+			},
+		},
+		"ResultsDue": {
+			reason: "The Function should close a poll past its deadline and record that its results are due before posting them",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
 						Composite: &fnv1beta1.Resource{
-							Resource: resource.MustStructJSON(`
-							{
+							Resource: resource.MustStructJSON(`{
 								"apiVersion": "kndp.io/v1alpha1",
-								"kind": "Meal",
-								"metadata": {
-									"annotations": {
-										"poll.fn.kndp.io/last-sent-time": "100000000000000"
-									},
-									"name": "meal"
-								},
-								"spec": {
-									"deliveryTime": "",
-									"dueOrderTime": "100000000000000",
-									"dueTakeTime": "",
-									"employeeRefs": [],
-									"status": ""
-								},
-								"status": {
-									"conditions": [
-										{
-											"lastTransitionTime": null,
-											"reason": "",
-											"status": "False",
-											"type": "Synced"
-										}
-									]
-								}
-							}`,
-							),
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "dueOrderTime": 1},
+								"status": {"phase": "Open", "lastNotificationTime": 1}
+							}`),
 						},
-						Resources: map[string]*fnv1beta1.Resource{
-							"": {
-								Resource: resource.MustStructJSON(`
-								{
-									"apiVersion": "kubernetes.crossplane.io/v1alpha1",
-									"kind": "Object",
-									"metadata": {
-									  "name": ""
-									},
-									"spec": {
-									  "forProvider": {
-										"manifest": {
-										  "apiVersion": "apps/v1",
-										  "kind": "Deployment",
-										  "metadata": {
-											"name": "",
-											"namespace": "default"
-										  },
-										  "spec": {
-											"replicas": 1,
-											"selector": {
-											  "matchLabels": {
-												"app": "poll"
-											  }
-											},
-											"template": {
-											  "metadata": {
-												"labels": {
-												  "app": "poll"
-												}
-											  },
-											  "spec": {
-												"containers": [
-												  {
-													"envFrom": [
-													  {
-														"configMapRef": {
-														  "name": ""
-														}
-													  }
-													],
-													"image": "",
-													"name": "poll-container",
-													"ports": [
-													  {
-														"containerPort": 80
-													  }
-													]
-												  }
-												],
-												"serviceAccountName": ""
-											  }
-											}
-										  }
-										}
-									  },
-									  "managementPolicy": "Default",
-									  "providerConfigRef": {
-										"name": ""
-									  }
-									}
-								  }
-								`),
-							},
+					},
+					ExtraResources: polls,
+				},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_NORMAL,
+					Message:  `Posting the results of poll "lunch"`,
+				}},
+				conditions: []xpv1.Condition{
+					{Type: typeSlackReachable, Status: corev1.ConditionTrue, Reason: reasonAvailable},
+					{Type: typeResultsPosted, Status: corev1.ConditionFalse, Reason: reasonPosting},
+				},
+			},
+		},
+		"ResultsPosted": {
+			reason: "The Function should post the results once they're recorded as due and report it",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "kndp.io/v1alpha1",
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "dueOrderTime": 1},
+								"status": {"phase": "Closed", "done": true, "lastNotificationTime": 1, "results": {"round": "1", "requestedAt": 1}}
+							}`),
 						},
 					},
+					ExtraResources: polls,
+				},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_NORMAL,
+					Message:  `Posted the results of poll "lunch"`,
+				}},
+				conditions: []xpv1.Condition{
+					{Type: typeSlackReachable, Status: corev1.ConditionTrue, Reason: reasonAvailable},
+					{Type: typeResultsPosted, Status: corev1.ConditionTrue, Reason: reasonPosted},
+				},
+			},
+		},
+		"PostFailed": {
+			reason: "The Function should return a warning and keep the poll Closed if the results can't be posted",
+			args: args{
+				req: &fnv1beta1.RunFunctionRequest{
+					Input: input,
+					Observed: &fnv1beta1.State{
+						Composite: &fnv1beta1.Resource{
+							Resource: resource.MustStructJSON(`{
+								"apiVersion": "kndp.io/v1alpha1",
+								"kind": "Poll",
+								"metadata": {"name": "lunch"},
+								"spec": {"title": "lunch", "schedule": "0 11 * * 1-5"},
+								"status": {"phase": "Closed", "done": true, "lastNotificationTime": 1, "results": {"round": "1", "requestedAt": 1}}
+							}`),
+						},
+					},
+					ExtraResources: polls,
+				},
+				fail: map[string]string{"chat.postMessage": "channel_not_found"},
+			},
+			want: want{
+				results: []*fnv1beta1.Result{{
+					Severity: fnv1beta1.Severity_SEVERITY_WARNING,
+					Message:  "cannot post results: channel_not_found",
+				}},
+				conditions: []xpv1.Condition{
+					{Type: typeSlackReachable, Status: corev1.ConditionTrue, Reason: reasonAvailable},
+					{Type: typeResultsPosted, Status: corev1.ConditionFalse, Reason: reasonPostFailed, Message: "cannot post results: channel_not_found"},
 				},
 			},
 		},
@@ -264,16 +301,812 @@ func TestRunFunction(t *testing.T) {
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			f := &Function{log: logging.NewNopLogger()}
-			rsp, err := f.RunFunction(tc.args.ctx, tc.args.req)
+			srv := slacktest.NewServer()
+			defer srv.Close()
+			for method, err := range tc.args.fail {
+				srv.Fail(method, err)
+			}
+			f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+			rsp, err := f.RunFunction(context.Background(), tc.args.req)
+			if err != nil {
+				t.Fatalf("%s\nf.RunFunction(...): %v", tc.reason, err)
+			}
 
-			if diff := cmp.Diff(tc.want.rsp, rsp, protocmp.Transform()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want rsp, +got rsp:\n%s", tc.reason, diff)
+			if diff := cmp.Diff(tc.want.results, rsp.GetResults(), protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want results, +got results:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(durationpb.New(response.DefaultTTL), rsp.GetMeta().GetTtl(), protocmp.Transform()); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want ttl, +got ttl:\n%s", tc.reason, diff)
 			}
 
-			if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
-				t.Errorf("%s\nf.RunFunction(...): -want err, +got err:\n%s", tc.reason, diff)
+			var conditions []xpv1.Condition
+			if c := rsp.GetDesired().GetComposite(); c != nil {
+				xr := composite.New()
+				if err := resource.AsObject(c.GetResource(), xr); err != nil {
+					t.Fatalf("cannot convert desired composite: %v", err)
+				}
+				conditions = []xpv1.Condition{xr.GetCondition(typeSlackReachable), xr.GetCondition(typeResultsPosted)}
 			}
+			if diff := cmp.Diff(tc.want.conditions, conditions, cmpopts.IgnoreFields(xpv1.Condition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want conditions, +got conditions:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// roundFixtureTime is the time the round of the status patches in
+// testdata/round opened at.
+const roundFixtureTime = 1734951600
+
+// TestPollRound runs a round of a poll against a fake Slack. The notifier and
+// the collector run outside the function, the status patches they write when
+// opening the round and recording votes are applied between the runs. The
+// patches are read from testdata/round, which the tests of their modules
+// check them against, so the three can't drift apart.
+func TestPollRound(t *testing.T) {
+	srv := slacktest.NewServer(slacktest.User("U1", "alice"), slacktest.User("U2", "bob"), slacktest.Bot("B1", "pollbot"))
+	defer srv.Close()
+	f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+
+	input := resource.MustStructJSON(`{
+		"apiVersion": "template.fn.crossplane.io/v1beta1",
+		"kind": "Input",
+		"deploymentName": "slack-collector",
+		"deploymentImage": "slack-collector:latest",
+		"cronJobImage": "slack-notify:latest"
+	}`)
+	xr := composite.New()
+	if err := resource.AsObject(resource.MustStructJSON(`{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind": "Poll",
+		"metadata": {"name": "lunch"},
+		"spec": {
+			"title": "lunch",
+			"schedule": "0 11 * * 1-5",
+			"dueOrderTime": "15m",
+			"messages": {"result": "Lunch is decided"}
+		}
+	}`), xr); err != nil {
+		t.Fatal(err)
+	}
+
+	// run runs the function on the poll and continues with the desired poll.
+	run := func(step string) {
+		t.Helper()
+		rsp, err := f.RunFunction(context.Background(), &fnv1beta1.RunFunctionRequest{
+			Input:          input,
+			Observed:       &fnv1beta1.State{Composite: &fnv1beta1.Resource{Resource: resource.MustStructObject(xr)}},
+			ExtraResources: map[string]*fnv1beta1.Resources{"polls": {}},
 		})
+		if err != nil {
+			t.Fatalf("%s: f.RunFunction(...): %v", step, err)
+		}
+		for _, r := range rsp.GetResults() {
+			if r.GetSeverity() != fnv1beta1.Severity_SEVERITY_NORMAL {
+				t.Errorf("%s: f.RunFunction(...): unexpected result: %s", step, r.GetMessage())
+			}
+		}
+		// Crossplane applies the desired status to the poll.
+		desired := composite.New()
+		if err := resource.AsObject(rsp.GetDesired().GetComposite().GetResource(), desired); err != nil {
+			t.Fatalf("%s: cannot convert desired composite: %v", step, err)
+		}
+		status, _ := desired.GetValue("status")
+		for field, value := range status.(map[string]interface{}) {
+			if err := xr.SetValue("status."+field, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	// The round of the patches opened at roundFixtureTime, it opens now
+	// instead.
+	now := time.Now().Unix()
+	// apply applies a status patch of the notifier or the collector to the
+	// poll, the way the API server does.
+	apply := func(name string) {
+		t.Helper()
+		patch, err := os.ReadFile(filepath.Join("testdata", "round", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		patch = []byte(strings.ReplaceAll(string(patch), strconv.Itoa(roundFixtureTime), strconv.FormatInt(now, 10)))
+		current, err := json.Marshal(xr.Object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patched, err := jsonpatch.MergePatch(current, patch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		xr = composite.New()
+		if err := json.Unmarshal(patched, &xr.Object); err != nil {
+			t.Fatal(err)
+		}
+	}
+	phase := func() string {
+		phase, _ := xr.GetString("status.phase")
+		return phase
+	}
+
+	run("Pending")
+	if diff := cmp.Diff(0, len(srv.Messages())); diff != "" {
+		t.Errorf("Pending: nothing should be posted before the round opens: -want, +got:\n%s", diff)
+	}
+
+	// The notifier opens the round.
+	apply("open.json")
+	run("Open")
+	if closeTime, _ := xr.GetInteger("status.closeTime"); closeTime != now+900 {
+		t.Errorf("Open: the round should close 15 minutes after it opened: want %d, got %d", now+900, closeTime)
+	}
+
+	apply("vote-alice.json")
+	run("Vote")
+	if diff := cmp.Diff("Open", phase()); diff != "" {
+		t.Errorf("Vote: the poll should stay open until everyone voted: -want, +got:\n%s", diff)
+	}
+
+	apply("vote-bob.json")
+	run("Close")
+	if diff := cmp.Diff("Closed", phase()); diff != "" {
+		t.Errorf("Close: the poll should close once everyone voted: -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(0, len(srv.Messages())); diff != "" {
+		t.Errorf("Close: the results should be posted once they're recorded as due: -want, +got:\n%s", diff)
+	}
+
+	// The status written after posting the results is lost, the reconcile is
+	// retried with the poll as it was.
+	observed := resource.MustStructObject(xr)
+	run("Post")
+	xr = composite.New()
+	if err := resource.AsObject(observed, xr); err != nil {
+		t.Fatal(err)
+	}
+	run("Retry")
+	if diff := cmp.Diff("ResultsPosted", phase()); diff != "" {
+		t.Errorf("Retry: -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(corev1.ConditionTrue, xr.GetCondition(typeResultsPosted).Status); diff != "" {
+		t.Errorf("Retry: -want ResultsPosted, +got ResultsPosted:\n%s", diff)
+	}
+	messages := srv.Messages()
+	if len(messages) != 1 || len(messages[0].Attachments) == 0 {
+		t.Fatalf("Retry: the results should be posted once, got %+v", messages)
+	}
+	if diff := cmp.Diff("Lunch is decided\nYes: 1\nNo: 1", messages[0].Attachments[0].Text); diff != "" {
+		t.Errorf("Retry: -want results, +got results:\n%s", diff)
+	}
+	if ts, _ := xr.GetString("status.results.ts"); ts != messages[0].Timestamp {
+		t.Errorf("Retry: the results message should be recorded: want ts %q, got %q", messages[0].Timestamp, ts)
+	}
+
+	run("ResultsPosted")
+	if diff := cmp.Diff(1, len(srv.Messages())); diff != "" {
+		t.Errorf("ResultsPosted: the results should not be posted again: -want, +got:\n%s", diff)
+	}
+}
+
+func TestSlackEnv(t *testing.T) {
+	type args struct {
+		spec        string
+		channel     string
+		credentials *slackchannel.SecretKeyRef
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []interface{}
+	}{
+		"Fallback": {
+			reason: "Polls without their own channel and credentials should use the environment of the workloads",
+			args:   args{spec: `{}`, channel: "C0123456789"},
+			want:   []interface{}{},
+		},
+		"Poll": {
+			reason: "The resolved channel and the credentials of the poll should be passed to the workloads",
+			args: args{
+				spec:        `{"channel": "#lunch"}`,
+				channel:     "C0123456789",
+				credentials: &slackchannel.SecretKeyRef{Name: "slack", Key: "token"},
+			},
+			want: []interface{}{
+				map[string]interface{}{"name": "SLACK_CHANEL_ID", "value": "C0123456789"},
+				map[string]interface{}{"name": "SLACK_API_TOKEN", "valueFrom": map[string]interface{}{
+					"secretKeyRef": map[string]interface{}{"name": "slack", "key": "token"},
+				}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xr := &resource.Composite{Resource: composite.New()}
+			if err := resource.AsObject(resource.MustStructJSON(`{"apiVersion": "kndp.io/v1alpha1", "kind": "Poll", "spec": `+tc.args.spec+`}`), xr.Resource); err != nil {
+				t.Fatal(err)
+			}
+			got := slackEnv(xr, tc.args.channel, tc.args.credentials)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nslackEnv(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestComposedName(t *testing.T) {
+	type args struct {
+		name   string
+		uid    string
+		suffix string
+		limit  int
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"UID": {
+			reason: "Names should carry the start of the UID of the poll",
+			args:   args{name: "lunch", uid: "0a1b2c3d-4e5f-6789-abcd-ef0123456789", suffix: "slack-notify", limit: maxCronJobNameLength},
+			want:   "lunch-0a1b2c3d-slack-notify",
+		},
+		"NoUID": {
+			reason: "Polls without a UID, like rendered ones, should be named after the poll",
+			args:   args{name: "lunch", suffix: "slack-notify", limit: maxCronJobNameLength},
+			want:   "lunch-slack-notify",
+		},
+		"Long": {
+			reason: "Long poll names should be shortened to fit the limit, keeping the UID and the suffix",
+			args:   args{name: "team-lunch-orders-for-the-whole-office-on-fridays", uid: "0a1b2c3d-4e5f", suffix: "slack-notify", limit: maxCronJobNameLength},
+			want:   "team-lunch-orders-for-the-whol-0a1b2c3d-slack-notify",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			xr := &resource.Composite{Resource: composite.New()}
+			xr.Resource.SetName(tc.args.name)
+			xr.Resource.SetUID(types.UID(tc.args.uid))
+			got := composedName(xr, tc.args.suffix, tc.args.limit)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ncomposedName(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// TestComposedResources checks that polls running their own collectors don't
+// share any composed resource: every poll runs its own notifier and
+// collector, in the namespace of the input.
+func TestComposedResources(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+
+	input := resource.MustStructJSON(`{
+		"apiVersion": "template.fn.crossplane.io/v1beta1",
+		"kind": "Input",
+		"deploymentName": "slack-collector",
+		"deploymentImage": "slack-collector:latest",
+		"cronJobImage": "slack-notify:latest",
+		"namespace": "polls",
+		"collectorMode": "PerPoll"
+	}`)
+	names := map[string]string{}
+	for _, uid := range []string{"0a1b2c3d-0000", "9f8e7d6c-0000"} {
+		rsp, err := f.RunFunction(context.Background(), &fnv1beta1.RunFunctionRequest{
+			Input: input,
+			Observed: &fnv1beta1.State{Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(`{
+				"apiVersion": "kndp.io/v1alpha1",
+				"kind": "Poll",
+				"metadata": {"name": "lunch", "uid": "` + uid + `"},
+				"spec": {"title": "lunch", "schedule": "0 11 * * 1-5"}
+			}`)}},
+		})
+		if err != nil {
+			t.Fatalf("f.RunFunction(...): %v", err)
+		}
+		keys := []string{}
+		for key, r := range rsp.GetDesired().GetResources() {
+			keys = append(keys, key)
+			object := composite.New()
+			if err := resource.AsObject(r.GetResource(), object); err != nil {
+				t.Fatal(err)
+			}
+			manifest := object.Object["spec"].(map[string]interface{})["forProvider"].(map[string]interface{})["manifest"].(map[string]interface{})
+			metadata := manifest["metadata"].(map[string]interface{})
+			if diff := cmp.Diff("polls", metadata["namespace"]); diff != "" {
+				t.Errorf("%s: the workloads should run in the namespace of the input: -want, +got:\n%s", key, diff)
+			}
+			for _, name := range []string{"Object/" + object.GetName(), manifest["kind"].(string) + "/" + metadata["name"].(string)} {
+				if other, ok := names[name]; ok {
+					t.Errorf("%s: %s is also composed by %s", uid, name, other)
+				}
+				names[name] = uid
+			}
+		}
+		// Crossplane tracks the resources of existing polls by these keys.
+		slices.Sort(keys)
+		if diff := cmp.Diff([]string{"ingress-collector", "service-collector", "slack-collector", "slack-notify-cronjob"}, keys); diff != "" {
+			t.Errorf("%s: the composed resources should keep their keys: -want, +got:\n%s", uid, diff)
+		}
+	}
+	if diff := cmp.Diff(16, len(names)); diff != "" {
+		t.Errorf("every poll should compose a collector and a notifier: -want names, +got names:\n%s", diff)
+	}
+}
+
+// TestSharedCollector checks that polls share one collector by default, which
+// one of them composes.
+func TestSharedCollector(t *testing.T) {
+	poll := func(name, created string, done bool) string {
+		status := ""
+		if done {
+			status = `, "status": {"phase": "ResultsPosted", "done": true, "lastNotificationTime": 1}`
+		}
+		return `{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "` + name + `", "uid": "` + name + `-uid", "creationTimestamp": "` + created + `"},
+			"spec": {"title": "lunch", "schedule": "0 11 * * 1-5"}` + status + `
+		}`
+	}
+	object := func(policies string) map[string]*fnv1beta1.Resource {
+		return map[string]*fnv1beta1.Resource{"slack-collector": {Resource: resource.MustStructJSON(`{
+			"apiVersion": "kubernetes.crossplane.io/v1alpha2",
+			"kind": "Object",
+			"metadata": {"name": "a-slack-collector"},
+			"spec": {"managementPolicies": ` + policies + `}
+		}`)}}
+	}
+	polls := func(others ...string) map[string]*fnv1beta1.Resources {
+		items := []*fnv1beta1.Resource{}
+		for _, o := range others {
+			items = append(items, &fnv1beta1.Resource{Resource: resource.MustStructJSON(o)})
+		}
+		return map[string]*fnv1beta1.Resources{"polls": {Items: items}}
+	}
+
+	type want struct {
+		composed bool
+		policies []string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		xr       string
+		observed map[string]*fnv1beta1.Resource
+		extra    map[string]*fnv1beta1.Resources
+		want     want
+	}{
+		"Leader": {
+			reason: "The oldest poll needing the collector should compose it and keep it when handing it over",
+			xr:     poll("a", "2026-01-01T00:00:00Z", false),
+			extra:  polls(poll("a", "2026-01-01T00:00:00Z", false), poll("b", "2026-02-01T00:00:00Z", false)),
+			want:   want{composed: true, policies: keepPolicies},
+		},
+		"Follower": {
+			reason: "Polls younger than the one composing the collector should only compose their notifier",
+			xr:     poll("b", "2026-02-01T00:00:00Z", false),
+			extra:  polls(poll("a", "2026-01-01T00:00:00Z", false), poll("b", "2026-02-01T00:00:00Z", false)),
+			want:   want{},
+		},
+		"LeaderAfterClosedPoll": {
+			reason: "Older polls that don't need the collector anymore shouldn't compose it",
+			xr:     poll("b", "2026-02-01T00:00:00Z", false),
+			extra:  polls(poll("a", "2026-01-01T00:00:00Z", true)),
+			want:   want{composed: true, policies: keepPolicies},
+		},
+		"HandOver": {
+			reason:   "A poll that doesn't need the collector should drop it while another poll needs it",
+			xr:       poll("a", "2026-01-01T00:00:00Z", true),
+			observed: object(`["Observe", "Create", "Update", "LateInitialize"]`),
+			extra:    polls(poll("b", "2026-02-01T00:00:00Z", false)),
+			want:     want{},
+		},
+		"Release": {
+			reason:   "The last poll composing the collector should release it once no poll needs it",
+			xr:       poll("a", "2026-01-01T00:00:00Z", true),
+			observed: object(`["Observe", "Create", "Update", "LateInitialize"]`),
+			extra:    polls(),
+			want:     want{composed: true, policies: releasePolicies},
+		},
+		"Delete": {
+			reason:   "A released collector should be dropped, which deletes it",
+			xr:       poll("a", "2026-01-01T00:00:00Z", true),
+			observed: object(`["*"]`),
+			extra:    polls(),
+			want:     want{},
+		},
+	}
+
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			rsp, err := f.RunFunction(context.Background(), &fnv1beta1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input",
+					"deploymentName": "slack-collector",
+					"deploymentImage": "slack-collector:latest",
+					"cronJobImage": "slack-notify:latest"
+				}`),
+				Observed: &fnv1beta1.State{
+					Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(tc.xr)},
+					Resources: tc.observed,
+				},
+				ExtraResources: tc.extra,
+			})
+			if err != nil {
+				t.Fatalf("f.RunFunction(...): %v", err)
+			}
+			if _, ok := rsp.GetDesired().GetResources()[string(notifierKey)]; !ok {
+				t.Errorf("%s\nf.RunFunction(...): every poll should compose its notifier", tc.reason)
+			}
+
+			got := want{}
+			if r, ok := rsp.GetDesired().GetResources()["slack-collector"]; ok {
+				deployment := composite.New()
+				if err := resource.AsObject(r.GetResource(), deployment); err != nil {
+					t.Fatal(err)
+				}
+				got.composed = true
+				got.policies, _ = deployment.GetStringArray("spec.managementPolicies")
+				if name, _ := deployment.GetString("spec.forProvider.manifest.metadata.name"); name != "slack-collector" {
+					t.Errorf("%s\nf.RunFunction(...): the shared collector should be named after the input, got %q", tc.reason, name)
+				}
+				env, _ := deployment.GetValue("spec.forProvider.manifest.spec.template.spec.containers[0].env[0]")
+				if diff := cmp.Diff(map[string]interface{}{"name": "SLACK_COLLECTOR_PATH", "value": "/events"}, env); diff != "" {
+					t.Errorf("%s\nf.RunFunction(...): the shared collector should keep receiving interactions on /events: -want, +got:\n%s", tc.reason, diff)
+				}
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+
+			selector := rsp.GetRequirements().GetExtraResources()["polls"].GetMatchLabels().GetLabels()
+			if diff := cmp.Diff(map[string]string{collectorLabel: "default.slack-collector"}, selector); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): the polls sharing the collector should be required: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestNeedsCollector(t *testing.T) {
+	const day = int64(24 * 60 * 60)
+	poll := func(organizers bool, status string) *resource.Composite {
+		spec := `{"title": "lunch"}`
+		if organizers {
+			spec = `{"title": "lunch", "organizers": ["alice"]}`
+		}
+		xr := &resource.Composite{Resource: composite.New()}
+		if err := resource.AsObject(resource.MustStructJSON(`{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "lunch"},
+			"spec": `+spec+`,
+			"status": `+status+`
+		}`), xr.Resource); err != nil {
+			t.Fatal(err)
+		}
+		return xr
+	}
+
+	cases := map[string]struct {
+		reason string
+		xr     *resource.Composite
+		now    int64
+		want   bool
+	}{
+		"Open": {
+			reason: "Polls accepting votes should need a collector",
+			xr:     poll(false, `{"phase": "Open"}`),
+			want:   true,
+		},
+		"ResultsPosted": {
+			reason: "Polls without organizers shouldn't need a collector once their results are posted",
+			xr:     poll(false, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}}`),
+			now:    1001,
+			want:   false,
+		},
+		"Reopenable": {
+			reason: "Organizers should be able to reopen a round right after its results are posted",
+			xr:     poll(true, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}}`),
+			now:    1001,
+			want:   true,
+		},
+		"CancelledReopenable": {
+			reason: "Organizers should be able to reopen a round right after it was cancelled",
+			xr:     poll(true, `{"phase": "Cancelled", "phaseTimestamps": {"cancelled": 1000}}`),
+			now:    1001,
+			want:   true,
+		},
+		"ReopenWindowPassed": {
+			reason: "Organizers shouldn't keep the collector running once the reopen window passed",
+			xr:     poll(true, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}}`),
+			now:    1000 + day,
+			want:   false,
+		},
+		"NextRoundDue": {
+			reason: "Organizers shouldn't reopen a round once the next one is due",
+			xr:     poll(true, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}, "nextRunTime": 2000}`),
+			now:    2000,
+			want:   false,
+		},
+		"Closed": {
+			reason: "Organizers shouldn't keep the collector running while the results are pending",
+			xr:     poll(true, `{"phase": "Closed", "phaseTimestamps": {"closed": 1000}}`),
+			now:    1001,
+			want:   false,
+		},
+		"Unpaid": {
+			reason: "Voters should be able to mark their share as paid until the ledger is settled",
+			xr:     poll(false, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}, "ledger": {"createdAt": 1000, "entries": [{"name": "alice", "amount": [], "paid": true}, {"name": "bob", "amount": [], "paid": false}]}}`),
+			now:    1000 + day,
+			want:   true,
+		},
+		"PendingPickup": {
+			reason: "Recipients should be able to confirm the pickup of their order",
+			xr:     poll(false, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}, "delivery": {"orderedAt": 1000, "recipients": ["alice", "bob"], "deliveredAt": 2000, "pickedUp": ["alice"]}}`),
+			now:    1000 + day,
+			want:   true,
+		},
+		"PickedUp": {
+			reason: "An order everyone picked up shouldn't keep the collector running",
+			xr:     poll(false, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}, "delivery": {"orderedAt": 1000, "recipients": ["alice", "bob"], "deliveredAt": 2000, "pickedUp": ["alice", "bob"]}}`),
+			now:    1000 + day,
+			want:   false,
+		},
+		"Settled": {
+			reason: "A settled ledger shouldn't keep the collector running",
+			xr:     poll(false, `{"phase": "ResultsPosted", "phaseTimestamps": {"resultsPosted": 1000}, "ledger": {"createdAt": 1000, "entries": [{"name": "bob", "amount": [], "paid": false}], "settledAt": 2000}}`),
+			now:    1000 + day,
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := needsCollector(tc.xr, tc.now)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nneedsCollector(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCollectorRef(t *testing.T) {
+	long := strings.Repeat("n", 63)
+	cases := map[string]struct {
+		reason    string
+		namespace string
+		name      string
+		want      string
+	}{
+		"Short": {
+			reason:    "References that fit a label should name the namespace and the collector",
+			namespace: "default",
+			name:      "slack-collector",
+			want:      "default.slack-collector",
+		},
+		"Long": {
+			reason:    "References that don't fit a label should be shortened and end with a hash",
+			namespace: long,
+			name:      "slack-collector",
+			want:      long[:52] + "-792c5b5b52",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := collectorRef(tc.namespace, tc.name)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ncollectorRef(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if errs := validation.IsValidLabelValue(got); len(errs) > 0 {
+				t.Errorf("%s\ncollectorRef(...): invalid label value %q: %v", tc.reason, got, errs)
+			}
+		})
+	}
+	if collectorRef(long, "a") == collectorRef(long, "b") {
+		t.Errorf("collectorRef(...): shortened references of different collectors should differ")
+	}
+}
+
+// TestRequiredResources runs the function the way Crossplane does when it
+// requires resources: once without them, and again once they're fetched.
+// Only the second run counts, so only it should reach Slack.
+func TestRequiredResources(t *testing.T) {
+	srv := slacktest.NewServer(slacktest.User("U1", "alice"), slacktest.User("U2", "bob"))
+	defer srv.Close()
+	f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+
+	now := time.Now().Unix()
+	req := &fnv1beta1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{
+			"apiVersion": "template.fn.crossplane.io/v1beta1",
+			"kind": "Input",
+			"deploymentName": "slack-collector",
+			"deploymentImage": "slack-collector:latest",
+			"cronJobImage": "slack-notify:latest"
+		}`),
+		Observed: &fnv1beta1.State{Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(`{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "lunch"},
+			"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "dueOrderTime": "1h", "reminders": {"offsets": ["1h"]}},
+			"status": {"phase": "Open", "round": "` + strconv.FormatInt(now, 10) + `", "lastNotificationTime": ` + strconv.FormatInt(now, 10) + `}
+		}`)}},
+	}
+
+	rsp, err := f.RunFunction(context.Background(), req)
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	if _, ok := rsp.GetRequirements().GetExtraResources()["polls"]; !ok {
+		t.Fatalf("f.RunFunction(...): the polls sharing the collector should be required")
+	}
+	if diff := cmp.Diff(0, len(srv.Messages())); diff != "" {
+		t.Errorf("f.RunFunction(...): nothing should be sent before the required resources are fetched: -want, +got:\n%s", diff)
+	}
+
+	req.ExtraResources = map[string]*fnv1beta1.Resources{"polls": {}}
+	if _, err := f.RunFunction(context.Background(), req); err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	reminded := []string{}
+	for _, m := range srv.Messages() {
+		reminded = append(reminded, m.Channel)
+	}
+	slices.Sort(reminded)
+	if diff := cmp.Diff([]string{"U1", "U2"}, reminded); diff != "" {
+		t.Errorf("f.RunFunction(...): every member should be reminded once: -want, +got:\n%s", diff)
+	}
+}
+
+// TestCredentialsRequirement checks that Crossplane only fetches the Secret
+// the poll references for it, not the credentials of every poll.
+func TestCredentialsRequirement(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+
+	rsp, err := f.RunFunction(context.Background(), &fnv1beta1.RunFunctionRequest{
+		Input: resource.MustStructJSON(`{
+			"apiVersion": "template.fn.crossplane.io/v1beta1",
+			"kind": "Input",
+			"deploymentName": "slack-collector",
+			"deploymentImage": "slack-collector:latest",
+			"cronJobImage": "slack-notify:latest"
+		}`),
+		Observed: &fnv1beta1.State{Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(`{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "lunch"},
+			"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "slackCredentialsSecretRef": {"name": "lunch-slack"}}
+		}`)}},
+	})
+	if err != nil {
+		t.Fatalf("f.RunFunction(...): %v", err)
+	}
+	selector := rsp.GetRequirements().GetExtraResources()["credentials"].GetMatchLabels().GetLabels()
+	if diff := cmp.Diff(map[string]string{slackchannel.CredentialsLabel: "lunch-slack", slackchannel.CredentialsNamespaceLabel: "default"}, selector); diff != "" {
+		t.Errorf("f.RunFunction(...): only the referenced Secret should be required: -want, +got:\n%s", diff)
+	}
+}
+
+// TestSharedCredentials checks that polls with their own Slack credentials
+// are warned that the shared collector doesn't use them.
+func TestSharedCredentials(t *testing.T) {
+	secret := resource.MustStructJSON(`{
+		"apiVersion": "v1",
+		"kind": "Secret",
+		"metadata": {
+			"namespace": "default",
+			"name": "lunch-slack",
+			"labels": {"poll.kndp.io/slack-credentials": "lunch-slack", "poll.kndp.io/slack-credentials-namespace": "default"}
+		},
+		"data": {"SLACK_API_TOKEN": "eG94Yi1sdW5jaA=="}
+	}`)
+	warning := "the shared collector doesn't use the Slack credentials of the poll, set collectorMode to PerPoll to use them"
+
+	cases := map[string]struct {
+		reason string
+		mode   string
+		want   bool
+	}{
+		"Shared": {
+			reason: "Polls with their own credentials should be warned that the shared collector doesn't use them",
+			mode:   collectorModeShared,
+			want:   true,
+		},
+		"PerPoll": {
+			reason: "Polls running their own collector use their credentials",
+			mode:   collectorModePerPoll,
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := slacktest.NewServer()
+			defer srv.Close()
+			f := &Function{log: logging.NewNopLogger(), slack: srv.Client()}
+
+			rsp, err := f.RunFunction(context.Background(), &fnv1beta1.RunFunctionRequest{
+				Input: resource.MustStructJSON(`{
+					"apiVersion": "template.fn.crossplane.io/v1beta1",
+					"kind": "Input",
+					"deploymentName": "slack-collector",
+					"deploymentImage": "slack-collector:latest",
+					"cronJobImage": "slack-notify:latest",
+					"collectorMode": "` + tc.mode + `"
+				}`),
+				Observed: &fnv1beta1.State{Composite: &fnv1beta1.Resource{Resource: resource.MustStructJSON(`{
+					"apiVersion": "kndp.io/v1alpha1",
+					"kind": "Poll",
+					"metadata": {"name": "lunch"},
+					"spec": {"title": "lunch", "schedule": "0 11 * * 1-5", "slackCredentialsSecretRef": {"name": "lunch-slack"}}
+				}`)}},
+				ExtraResources: map[string]*fnv1beta1.Resources{
+					"polls":       {},
+					"credentials": {Items: []*fnv1beta1.Resource{{Resource: secret}}},
+				},
+			})
+			if err != nil {
+				t.Fatalf("f.RunFunction(...): %v", err)
+			}
+			warned := false
+			for _, r := range rsp.GetResults() {
+				if r.GetMessage() == warning {
+					warned = true
+				}
+			}
+			if diff := cmp.Diff(tc.want, warned); diff != "" {
+				t.Errorf("%s\nf.RunFunction(...): -want warning, +got warning:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPollChannel(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.SetChannels(slacktest.Channel("C0987654321", "lunch"), slacktest.Channel("C0123456789", "dinner"))
+
+	xr := &resource.Composite{Resource: composite.New()}
+	if err := resource.AsObject(resource.MustStructJSON(`{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind": "Poll",
+		"metadata": {"name": "lunch"},
+		"spec": {"title": "lunch", "channel": "#lunch"}
+	}`), xr.Resource); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := pollChannel(srv.Client(), xr); err != nil || id != "C0987654321" {
+		t.Fatalf("pollChannel(...): want C0987654321, got %q, %v", id, err)
+	}
+
+	// The channel can't be listed anymore, the recorded ID is reused.
+	srv.Fail("conversations.list", "ratelimited")
+	if id, err := pollChannel(srv.Client(), xr); err != nil || id != "C0987654321" {
+		t.Errorf("pollChannel(...): the resolved channel should be reused: want C0987654321, got %q, %v", id, err)
+	}
+
+	// Renaming the channel of the poll resolves it again.
+	srv.Fail("conversations.list", "")
+	if err := xr.Resource.SetString("spec.channel", "dinner"); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := pollChannel(srv.Client(), xr); err != nil || id != "C0123456789" {
+		t.Errorf("pollChannel(...): a changed channel should be resolved again: want C0123456789, got %q, %v", id, err)
+	}
+	got, _ := xr.Resource.GetValue("status.channel")
+	if diff := cmp.Diff(map[string]interface{}{"name": "dinner", "id": "C0123456789"}, got); diff != "" {
+		t.Errorf("pollChannel(...): -want, +got:\n%s", diff)
 	}
 }
@@ -27,4 +27,24 @@ type Input struct {
 	DeploymentImage    string `json:"deploymentImage"`
 	ServiceAccountName string `json:"serviceAccountName"`
 	CronJobImage       string `json:"cronJobImage"`
+
+	// CollectorMode is how the collectors of the polls run. Shared composes a
+	// single collector for all polls of the namespace behind /events, which
+	// routes the votes by the poll in the payload; it uses the Slack
+	// credentials of the function. PerPoll composes a collector for every
+	// poll behind its own path under /events, which the Slack app of the poll
+	// has to send its interactions to. Defaults to Shared.
+	// +optional
+	// +kubebuilder:validation:Enum=PerPoll;Shared
+	CollectorMode string `json:"collectorMode,omitempty"`
+
+	// Namespace is the namespace the notifier and the collector of the polls
+	// run in, the service account must exist in it. Defaults to default.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// TimeZone is the IANA time zone of the notification schedule of polls
+	// that don't set spec.timeZone. Defaults to Europe/Chisinau.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
 }
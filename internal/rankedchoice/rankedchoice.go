@@ -0,0 +1,152 @@
+// Package rankedchoice implements instant-runoff tallying of ranked ballots.
+package rankedchoice
+
+// Tally is the number of votes a candidate received in a round.
+type Tally struct {
+	Candidate string
+	Votes     int
+}
+
+// Round is a single counting round of an instant-runoff election.
+type Round struct {
+	// Tallies of the candidates still in the running, in candidate order.
+	Tallies []Tally
+	// Eliminated candidates at the end of this round.
+	Eliminated []string
+	// Exhausted is the number of ballots without any remaining preference.
+	Exhausted int
+}
+
+// Result is the outcome of an instant-runoff election.
+type Result struct {
+	// Winners holds a single winner, or every candidate still in the running
+	// when they are tied and none of them can be eliminated.
+	Winners []string
+	Rounds  []Round
+}
+
+// InstantRunoff counts the ballots in rounds. Every round each ballot counts
+// for its highest ranked candidate still in the running. A candidate with a
+// majority of the non-exhausted ballots wins, otherwise the candidate with the
+// fewest votes is eliminated and counting continues. Candidates tied for the
+// fewest votes are eliminated together only if they can't overtake anyone
+// else, see eliminate. Ballot entries that are not candidates, and repeated
+// entries, are ignored.
+func InstantRunoff(candidates []string, ballots [][]string) Result {
+	active := make(map[string]bool, len(candidates))
+	for _, c := range candidates {
+		active[c] = true
+	}
+
+	result := Result{}
+	for len(active) > 0 {
+		round := countRound(candidates, active, ballots)
+		result.Rounds = append(result.Rounds, round)
+
+		counted := len(ballots) - round.Exhausted
+		for _, t := range round.Tallies {
+			if counted > 0 && t.Votes*2 > counted {
+				result.Winners = []string{t.Candidate}
+				return result
+			}
+		}
+
+		eliminated := eliminate(result.Rounds)
+		if len(eliminated) == 0 {
+			// Everyone left is tied, nobody can be eliminated.
+			if counted > 0 {
+				for _, t := range round.Tallies {
+					result.Winners = append(result.Winners, t.Candidate)
+				}
+			}
+			return result
+		}
+
+		result.Rounds[len(result.Rounds)-1].Eliminated = eliminated
+		for _, c := range eliminated {
+			delete(active, c)
+		}
+	}
+	return result
+}
+
+// eliminate returns the candidates eliminated at the end of the last round, or
+// none if all candidates left are tied. Candidates tied for the fewest votes
+// are eliminated together if their votes combined are fewer than those of any
+// other candidate, as none of them could win. Otherwise one of them is
+// eliminated: the one with the fewest votes in the latest earlier round that
+// tells them apart, or the last of them in candidate order.
+func eliminate(rounds []Round) []string {
+	tallies := rounds[len(rounds)-1].Tallies
+	lowest := -1
+	for _, t := range tallies {
+		if lowest == -1 || t.Votes < lowest {
+			lowest = t.Votes
+		}
+	}
+	tied := []string{}
+	combined, next := 0, -1
+	for _, t := range tallies {
+		switch {
+		case t.Votes == lowest:
+			tied = append(tied, t.Candidate)
+			combined += t.Votes
+		case next == -1 || t.Votes < next:
+			next = t.Votes
+		}
+	}
+	switch {
+	case next == -1:
+		return nil
+	case len(tied) == 1 || combined < next:
+		return tied
+	}
+
+	for i := len(rounds) - 2; i >= 0 && len(tied) > 1; i-- {
+		votes := make(map[string]int, len(rounds[i].Tallies))
+		for _, t := range rounds[i].Tallies {
+			votes[t.Candidate] = t.Votes
+		}
+		fewest := -1
+		for _, c := range tied {
+			if fewest == -1 || votes[c] < fewest {
+				fewest = votes[c]
+			}
+		}
+		remaining := []string{}
+		for _, c := range tied {
+			if votes[c] == fewest {
+				remaining = append(remaining, c)
+			}
+		}
+		tied = remaining
+	}
+	return tied[len(tied)-1:]
+}
+
+// countRound counts each ballot for its highest ranked active candidate.
+func countRound(candidates []string, active map[string]bool, ballots [][]string) Round {
+	votes := make(map[string]int, len(active))
+	exhausted := 0
+	for _, ballot := range ballots {
+		counted := false
+		for _, choice := range ballot {
+			if active[choice] {
+				votes[choice]++
+				counted = true
+				break
+			}
+		}
+		if !counted {
+			exhausted++
+		}
+	}
+
+	round := Round{Exhausted: exhausted}
+	for _, c := range candidates {
+		if active[c] {
+			round.Tallies = append(round.Tallies, Tally{Candidate: c, Votes: votes[c]})
+		}
+	}
+	return round
+}
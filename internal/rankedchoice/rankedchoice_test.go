@@ -0,0 +1,193 @@
+package rankedchoice
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInstantRunoff(t *testing.T) {
+	type args struct {
+		candidates []string
+		ballots    [][]string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   Result
+	}{
+		"NoBallots": {
+			reason: "Without ballots there should be no winner",
+			args: args{
+				candidates: []string{"pizza", "sushi"},
+			},
+			want: Result{
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 0}, {"sushi", 0}}},
+				},
+			},
+		},
+		"FirstRoundMajority": {
+			reason: "A candidate with a majority of first preferences should win immediately",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad"},
+				ballots: [][]string{
+					{"pizza", "sushi"},
+					{"pizza"},
+					{"sushi", "pizza"},
+				},
+			},
+			want: Result{
+				Winners: []string{"pizza"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 2}, {"sushi", 1}, {"salad", 0}}},
+				},
+			},
+		},
+		"RunoffChangesPluralityWinner": {
+			reason: "Votes of eliminated candidates should transfer to the next preference",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad"},
+				ballots: [][]string{
+					{"pizza"},
+					{"pizza"},
+					{"sushi", "salad"},
+					{"salad", "sushi"},
+					{"salad", "sushi"},
+				},
+			},
+			want: Result{
+				Winners: []string{"salad"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 2}, {"sushi", 1}, {"salad", 2}}, Eliminated: []string{"sushi"}},
+					{Tallies: []Tally{{"pizza", 2}, {"salad", 3}}},
+				},
+			},
+		},
+		"ExhaustedBallots": {
+			reason: "Ballots without remaining preferences should not count towards the majority",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad", "tacos"},
+				ballots: [][]string{
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"sushi"},
+					{"salad", "sushi"},
+					{"tacos"},
+				},
+			},
+			want: Result{
+				Winners: []string{"pizza"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 3}, {"sushi", 1}, {"salad", 1}, {"tacos", 1}}, Eliminated: []string{"tacos"}},
+					{Tallies: []Tally{{"pizza", 3}, {"sushi", 1}, {"salad", 1}}, Exhausted: 1},
+				},
+			},
+		},
+		"BatchElimination": {
+			reason: "Candidates tied for the fewest votes should be eliminated together if they can't overtake anyone combined",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad", "tacos"},
+				ballots: [][]string{
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"sushi"},
+					{"sushi"},
+					{"sushi"},
+					{"salad", "pizza"},
+					{"tacos"},
+				},
+			},
+			want: Result{
+				Winners: []string{"pizza"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 3}, {"sushi", 3}, {"salad", 1}, {"tacos", 1}}, Eliminated: []string{"salad", "tacos"}},
+					{Tallies: []Tally{{"pizza", 4}, {"sushi", 3}}, Exhausted: 1},
+				},
+			},
+		},
+		"TiedForLast": {
+			reason: "Only one of the candidates tied for the fewest votes should be eliminated if they could overtake the leader combined",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad"},
+				ballots: [][]string{
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"sushi", "salad"},
+					{"sushi", "salad"},
+					{"sushi", "salad"},
+					{"salad", "sushi"},
+					{"salad", "sushi"},
+					{"salad", "sushi"},
+				},
+			},
+			want: Result{
+				Winners: []string{"sushi"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 4}, {"sushi", 3}, {"salad", 3}}, Eliminated: []string{"salad"}},
+					{Tallies: []Tally{{"pizza", 4}, {"sushi", 6}}},
+				},
+			},
+		},
+		"TieBrokenByEarlierRound": {
+			reason: "Of the candidates tied for the fewest votes, the one with fewer votes in an earlier round should be eliminated",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad", "tacos"},
+				ballots: [][]string{
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"pizza"},
+					{"sushi", "pizza"},
+					{"sushi", "pizza"},
+					{"salad"},
+					{"salad"},
+					{"salad"},
+					{"tacos", "sushi", "pizza"},
+				},
+			},
+			want: Result{
+				Winners: []string{"pizza"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 4}, {"sushi", 2}, {"salad", 3}, {"tacos", 1}}, Eliminated: []string{"tacos"}},
+					{Tallies: []Tally{{"pizza", 4}, {"sushi", 3}, {"salad", 3}}, Eliminated: []string{"sushi"}},
+					{Tallies: []Tally{{"pizza", 7}, {"salad", 3}}},
+				},
+			},
+		},
+		"Tie": {
+			reason: "Candidates tied in the last round should all be reported as winners",
+			args: args{
+				candidates: []string{"pizza", "sushi", "salad"},
+				ballots: [][]string{
+					{"pizza"},
+					{"sushi"},
+					{"salad", "pizza"},
+					{"sushi", "pizza"},
+					{"pizza", "sushi"},
+					{"unknown", "salad"},
+				},
+			},
+			want: Result{
+				Winners: []string{"pizza", "sushi", "salad"},
+				Rounds: []Round{
+					{Tallies: []Tally{{"pizza", 2}, {"sushi", 2}, {"salad", 2}}},
+				},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := InstantRunoff(tc.args.candidates, tc.args.ballots)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nInstantRunoff(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
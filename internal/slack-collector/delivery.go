@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// Delivery tracks the delivery and pickup phases after an order is placed.
+type Delivery struct {
+	OrderedAt   int64    `json:"orderedAt"`
+	Recipients  []string `json:"recipients"`
+	DeliveredAt int64    `json:"deliveredAt,omitempty"`
+	PickedUp    []string `json:"pickedUp,omitempty"`
+	RemindedAt  int64    `json:"remindedAt,omitempty"`
+}
+
+// errNotARecipient is returned when a user who isn't part of the order confirms a pickup.
+var errNotARecipient = errors.New("user is not a recipient of the order")
+
+// markPickedUp records that the user picked up their order. The patch is
+// conditional on the resource version of the delivery it's based on and
+// retried on conflicts, so pickups confirmed at the same time are all
+// recorded.
+func markPickedUp(user, pollSlackName string, dynamicClient dynamic.Interface, ctx context.Context) error {
+	return retryOnConflict(ctx, func() error {
+		pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
+		if err != nil {
+			return err
+		}
+		delivery := pollResource.Status.Delivery
+		if delivery == nil {
+			return errNotARecipient
+		}
+		recipient := false
+		for _, name := range delivery.Recipients {
+			if name == user {
+				recipient = true
+				break
+			}
+		}
+		if !recipient {
+			return errNotARecipient
+		}
+		for _, name := range delivery.PickedUp {
+			if name == user {
+				return nil
+			}
+		}
+		delivery.PickedUp = append(delivery.PickedUp, user)
+
+		statusBytes, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": pollResource.GetObjectMeta().GetResourceVersion(),
+			},
+			"status": map[string]interface{}{
+				"delivery": delivery,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = dynamicClient.Resource(resourceId).Namespace("").Patch(ctx, pollResource.GetObjectMeta().GetName(), types.MergePatchType, statusBytes, metav1.PatchOptions{FieldManager: "slack-collector"}, "status")
+		return err
+	})
+}
+
+// handlePickedUp handles a click on the "Picked up" button.
+func handlePickedUp(userID, user, pollSlackName string, dynamicClient dynamic.Interface, ctx context.Context) {
+	err := markPickedUp(user, pollSlackName, dynamicClient, ctx)
+	switch {
+	case errors.Is(err, errNotARecipient):
+		respondMsg(userID, user, "There is no order waiting for you.", pollSlackName)
+	case err != nil:
+		fmt.Println("Error recording pickup:", err)
+	default:
+		respondMsg(userID, user, "Thanks, enjoy your meal!", pollSlackName)
+	}
+}
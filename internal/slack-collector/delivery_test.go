@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMarkPickedUpConcurrent(t *testing.T) {
+	const recipients = 20
+
+	names := []interface{}{}
+	want := []string{}
+	for i := 0; i < recipients; i++ {
+		name := fmt.Sprintf("user-%02d", i)
+		names = append(names, name)
+		want = append(want, name)
+	}
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata": map[string]interface{}{
+			"name":            "lunch",
+			"resourceVersion": "1",
+		},
+		"spec": map[string]interface{}{
+			"title": "lunch",
+		},
+		"status": map[string]interface{}{
+			"delivery": map[string]interface{}{
+				"orderedAt":  int64(100),
+				"recipients": names,
+			},
+		},
+	}}
+	client := newFakeClient(poll)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, recipients)
+	for _, name := range want {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			if err := markPickedUp(user, "lunch", slowReads{client}, ctx); err != nil {
+				errs <- err
+			}
+		}(name)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("markPickedUp(...): %v", err)
+	}
+
+	got, err := getK8sResource(client, ctx, "lunch", resourceId)
+	if err != nil {
+		t.Fatalf("getK8sResource(...): %v", err)
+	}
+	pickedUp := append([]string(nil), got.Status.Delivery.PickedUp...)
+	sort.Strings(pickedUp)
+	if diff := cmp.Diff(want, pickedUp); diff != "" {
+		t.Errorf("markPickedUp(...): pickups were lost: -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// fakeSlack is a fake Slack Web API that records the messages posted and the
+// views opened. It mirrors the slacktest package of the function, which this
+// module can't import.
+type fakeSlack struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	messages []postedMessage
+	views    []string
+}
+
+// postedMessage is a message posted to the fake Slack.
+type postedMessage struct {
+	Channel     string
+	User        string
+	Text        string
+	Attachments []slack.Attachment
+	Ephemeral   bool
+}
+
+func newFakeSlack() *fakeSlack {
+	s := &fakeSlack{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// use points the Slack client to the fake Slack for the test, through
+// SLACK_API_URL like in a deployment.
+func (s *fakeSlack) use(t *testing.T) {
+	t.Setenv("SLACK_API_URL", s.URL+"/")
+	previous := api
+	api = newSlackClient("xoxb-test")
+	t.Cleanup(func() { api = previous })
+}
+
+// posted returns the messages posted so far.
+func (s *fakeSlack) posted() []postedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]postedMessage(nil), s.messages...)
+}
+
+// openedView is a view opened on the fake Slack.
+type openedView struct {
+	TriggerID string                 `json:"trigger_id"`
+	View      slack.ModalViewRequest `json:"view"`
+}
+
+// opened returns the views opened so far.
+func (s *fakeSlack) opened() []openedView {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	views := make([]openedView, 0, len(s.views))
+	for _, v := range s.views {
+		view := openedView{}
+		_ = json.Unmarshal([]byte(v), &view)
+		views = append(views, view)
+	}
+	return views
+}
+
+func (s *fakeSlack) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var body interface{}
+	switch r.URL.Path[1:] {
+	case "chat.postMessage", "chat.postEphemeral":
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m := postedMessage{
+			Channel:   r.FormValue("channel"),
+			User:      r.FormValue("user"),
+			Text:      r.FormValue("text"),
+			Ephemeral: r.URL.Path == "/chat.postEphemeral",
+		}
+		if attachments := r.FormValue("attachments"); attachments != "" {
+			_ = json.Unmarshal([]byte(attachments), &m.Attachments)
+		}
+		s.messages = append(s.messages, m)
+		ts := fmt.Sprintf("%d.000000", len(s.messages))
+		body = map[string]interface{}{"ok": true, "channel": m.Channel, "ts": ts, "message_ts": ts}
+	case "views.open":
+		// Views are posted as JSON.
+		view, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.views = append(s.views, string(view))
+		body = map[string]interface{}{"ok": true, "view": map[string]string{"id": fmt.Sprintf("V%d", len(s.views))}}
+	default:
+		body = map[string]interface{}{"ok": false, "error": "unknown_method"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -3,52 +3,121 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 // SelectedOptionValue represents the structure of the selected option value from Slack.
 type SelectedOptionValue struct {
+	Type string `json:"type"`
 	User struct {
 		ID   string `json:"id"`
 		Name string `json:"name"`
 	} `json:"user"`
 	CallbackID string `json:"callback_id"`
-	Actions    []struct {
+	Channel    struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+	Actions []struct {
 		Name            string `json:"name"`
 		Type            string `json:"type"`
+		ActionID        string `json:"action_id"`
+		BlockID         string `json:"block_id"`
 		SelectedOptions []struct {
 			Value string `json:"value"`
 		} `json:"selected_options"`
+		SelectedOption struct {
+			Value string `json:"value"`
+		} `json:"selected_option"`
 	} `json:"actions"`
 	OriginalMessage struct {
 		Attachments []struct {
 			Title string `json:"title"`
 		} `json:"attachments"`
 	} `json:"original_message"`
+	TriggerID string `json:"trigger_id"`
+	View      struct {
+		CallbackID      string `json:"callback_id"`
+		PrivateMetadata string `json:"private_metadata"`
+		State           struct {
+			Values map[string]map[string]struct {
+				Value string `json:"value"`
+			} `json:"values"`
+		} `json:"state"`
+	} `json:"view"`
 }
 
 // Voter represents the structure of an Voter reference.
 type Voter struct {
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Choices []string          `json:"choices,omitempty"`
+	Answer  string            `json:"answer,omitempty"`
+	Answers map[string]string `json:"answers,omitempty"`
+	Order   map[string]int64  `json:"order,omitempty"`
+}
+
+// Option represents a single answer a voter can choose.
+type Option struct {
+	Value       string `json:"value"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Answer represents the type of answer a poll expects: choice, text or number.
+// Min and Max bound number answers.
+type Answer struct {
+	Type string   `json:"type,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// Question represents one question of a multi-question poll.
+type Question struct {
+	ID      string   `json:"id"`
+	Prompt  string   `json:"prompt"`
+	Options []Option `json:"options,omitempty"`
+}
+
+// MenuItem represents an item voters can order in a meal poll.
+type MenuItem struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency,omitempty"`
 }
 
 // Message represents the structure of a message.
 type Message struct {
-	Question string `json:"question"`
-	Response string `json:"response"`
-	Result   string `json:"result"`
+	Question       string `json:"question"`
+	Response       string `json:"response"`
+	Result         string `json:"result"`
+	Delivery       string `json:"delivery,omitempty"`
+	PickupReminder string `json:"pickupReminder,omitempty"`
+}
+
+// ClaimRef references the PollClaim the poll was created for.
+type ClaimRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 // Poll represents the structure of a poll.
@@ -56,25 +125,57 @@ type Poll struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec              struct {
-		DeliveryTime int64   `json:"deliveryTime"`
-		DueOrderTime int64   `json:"dueOrderTime"`
-		DueTakeTime  int64   `json:"dueTakeTime"`
-		Schedule     string  `json:"schedule"`
-		Voters       []Voter `json:"voters"`
-		Title        string  `json:"title"`
-		Messages     Message `json:"messages"`
+		ClaimRef     *ClaimRef          `json:"claimRef,omitempty"`
+		DeliveryTime int64              `json:"deliveryTime"`
+		DueOrderTime intstr.IntOrString `json:"dueOrderTime"`
+		CloseAt      string             `json:"closeAt,omitempty"`
+		DueTakeTime  int64              `json:"dueTakeTime"`
+		Schedule     string             `json:"schedule"`
+		Voters       []Voter            `json:"voters"`
+		Title        string             `json:"title"`
+		Messages     Message            `json:"messages"`
+		Options      []Option           `json:"options,omitempty"`
+		Selection    string             `json:"selection,omitempty"`
+		Method       string             `json:"method,omitempty"`
+		Answer       Answer             `json:"answer,omitempty"`
+		Questions    []Question         `json:"questions,omitempty"`
+		Menu         []MenuItem         `json:"menu,omitempty"`
+		Organizers   []string           `json:"organizers,omitempty"`
 	} `json:"spec"`
 	Status struct {
-		Done                 bool  `json:"done"`
-		LastNotificationTime int64 `json:"lastNotificationTime"`
+		Voters               []Voter         `json:"voters"`
+		VotersMigrated       bool            `json:"votersMigrated,omitempty"`
+		Done                 bool            `json:"done"`
+		LastNotificationTime int64           `json:"lastNotificationTime"`
+		Phase                Phase           `json:"phase,omitempty"`
+		PhaseTimestamps      PhaseTimestamps `json:"phaseTimestamps,omitempty"`
+		Round                string          `json:"round,omitempty"`
+		CloseTime            int64           `json:"closeTime,omitempty"`
+		Ledger               *Ledger         `json:"ledger,omitempty"`
+		Delivery             *Delivery       `json:"delivery,omitempty"`
 	} `json:"status"`
 }
 
+// errInvalidOption is returned when a vote does not match any of the poll options.
+var errInvalidOption = errors.New("selected option is not one of the poll options")
+
+// errPollClosed is returned when a vote arrives while the poll isn't Open.
+var errPollClosed = errors.New("poll is not open for votes")
+
+// maxQuantity is the largest quantity of a menu item a voter can order.
+const maxQuantity = 10
+
+// resourceId identifies the Poll composite resources.
+var resourceId = schema.GroupVersionResource{
+	Group:    "kndp.io",
+	Version:  "v1alpha1",
+	Resource: "polls",
+}
+
 var (
-	api      = slack.New(os.Getenv("SLACK_API_TOKEN"))
-	path     = os.Getenv("SLACK_COLLECTOR_PATH")
-	port     = os.Getenv("SLACK_COLLECTOR_PORT")
-	response string
+	api  Messenger = newSlackClient(os.Getenv("SLACK_API_TOKEN"))
+	path           = os.Getenv("SLACK_COLLECTOR_PATH")
+	port           = os.Getenv("SLACK_COLLECTOR_PORT")
 )
 
 // handleEventsEndpoint handles the events endpoint.
@@ -91,87 +192,385 @@ func handleEventsEndpoint(w http.ResponseWriter, r *http.Request, dynamicClient
 		fmt.Println("Error decoding JSON:", err)
 		return
 	}
-	selectedOption := data.Actions[0].SelectedOptions[0].Value
-	pollSlackName := data.CallbackID
+	if data.Type == "view_submission" {
+		handleViewSubmission(w, data, dynamicClient, ctx)
+		return
+	}
+	if len(data.Actions) == 0 || data.Actions[0].Name == "actionCancel" {
+		fmt.Println("No vote in payload")
+		return
+	}
+	if data.Actions[0].Name == "actionPickedUp" {
+		handlePickedUp(data.User.ID, data.User.Name, data.CallbackID, dynamicClient, ctx)
+		return
+	}
+	if action, ok := organizerActions[data.Actions[0].Name]; ok {
+		handleOrganizerAction(data, action, dynamicClient, ctx)
+		return
+	}
+	if data.Actions[0].Name == "actionPaid" {
+		handlePaid(data.User.ID, data.User.Name, data.CallbackID, dynamicClient, ctx)
+		return
+	}
+	if data.Actions[0].Name == "actionAnswer" {
+		err := openAnswerModal(data.TriggerID, data.CallbackID, dynamicClient, ctx)
+		if text := roundError(err); text != "" {
+			pollSlackName, _ := splitCallbackID(data.CallbackID)
+			respondEphemeral(data.Channel.ID, data.User.ID, data.User.Name, text, pollSlackName)
+		} else if err != nil {
+			fmt.Println("Error opening answer modal:", err)
+		}
+		return
+	}
+	callbackID := data.CallbackID
+	// Block kit messages carry the callback ID in the block ID instead.
+	// Questions of multi-question polls and menu items add their ID after a slash.
+	var questionID string
+	if data.Type == "block_actions" {
+		callbackID, questionID = splitBlockID(data.Actions[0].BlockID)
+	}
+	pollSlackName, round := splitCallbackID(callbackID)
+	selectedOptions := make([]string, 0, len(data.Actions[0].SelectedOptions))
+	for _, option := range data.Actions[0].SelectedOptions {
+		selectedOptions = append(selectedOptions, option.Value)
+	}
+	// Block kit static selects, used for rankings, send a single selected option.
+	if data.Actions[0].SelectedOption.Value != "" {
+		selectedOptions = append(selectedOptions, data.Actions[0].SelectedOption.Value)
+	}
+	actionID := data.Actions[0].ActionID
 
 	user := data.User.Name
 	userID := data.User.ID
 
-	err = patchVoterStatus(user, pollSlackName, selectedOption, dynamicClient, ctx)
+	response, err := patchVoter(user, pollSlackName, round, func(poll *Poll, voter Voter) (Voter, error) {
+		if questionID != "" && actionID == "order" {
+			return orderItem(poll, voter, questionID, selectedOptions)
+		}
+		if questionID != "" {
+			return answerQuestion(poll, voter, questionID, selectedOptions)
+		}
+		return castVote(poll, voter, actionID, selectedOptions)
+	}, dynamicClient, ctx)
+	if text := roundError(err); text != "" {
+		fmt.Println("Rejected vote from", user, "for", pollSlackName, ":", err)
+		respondEphemeral(data.Channel.ID, userID, user, text+" Your vote was not recorded.", pollSlackName)
+		return
+	}
+	if errors.Is(err, errInvalidOption) {
+		fmt.Println("Rejected vote from", user, "for", pollSlackName, ":", selectedOptions)
+		respondMsg(userID, user, fmt.Sprintf("%q is not a valid choice for this poll.", strings.Join(selectedOptions, ", ")), pollSlackName)
+		return
+	}
 	if err != nil {
 		fmt.Println("Error patching Voter status:", err)
+		respondMsg(userID, user, "Your vote could not be recorded, please try again.", pollSlackName)
+		return
+	}
+	selected := strings.Join(selectedOptions, ", ")
+	if rank, ok := actionRank(actionID); ok {
+		selected = fmt.Sprintf("%s as choice #%d", selected, rank)
 	}
-	respondMsg(userID, user, selectedOption, pollSlackName)
+	respondMsg(userID, user, response+"\n Selected: "+selected, pollSlackName)
 }
 
-// patchVoterStatus patches the employee reference status.
-func patchVoterStatus(user, pollSlackName, selectedOption string, dynamicClient dynamic.Interface, ctx context.Context) error {
-	resourceId := schema.GroupVersionResource{
-		Group:    "kndp.io",
-		Version:  "v1alpha1",
-		Resource: "polls",
+// pollOptions returns the options of the poll, falling back to Yes/No.
+func pollOptions(options []Option) []Option {
+	if len(options) == 0 {
+		return []Option{{Value: "Yes"}, {Value: "No"}}
 	}
-	pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
-	if err != nil {
-		return err
+	return options
+}
+
+// validOption checks if the selected value is one of the poll options.
+func validOption(options []Option, selectedOption string) bool {
+	for _, option := range pollOptions(options) {
+		if option.Value == selectedOption {
+			return true
+		}
 	}
-	response = pollResource.Spec.Messages.Response
-	foundUser := false
-	for i := range pollResource.Spec.Voters {
-		if pollResource.Spec.Voters[i].Name == user {
-			pollResource.Spec.Voters[i].Status = selectedOption
-			foundUser = true
-			break
+	return false
+}
+
+// validSelection checks the selected values against the poll options and selection mode.
+// Multiple selection polls accept an empty selection, which clears the vote.
+func validSelection(poll *Poll, selectedOptions []string) bool {
+	multiple := poll.Spec.Selection == "multiple" && poll.Spec.Method != "rankedChoice"
+	if !multiple && len(selectedOptions) != 1 {
+		return false
+	}
+	for _, selectedOption := range selectedOptions {
+		if !validOption(poll.Spec.Options, selectedOption) {
+			return false
 		}
 	}
+	return true
+}
+
+// actionRank returns the rank encoded in the action ID of a ranking select.
+func actionRank(actionID string) (int, bool) {
+	rank, found := strings.CutPrefix(actionID, "rank_")
+	if !found {
+		return 0, false
+	}
+	n, err := strconv.Atoi(rank)
+	if err != nil || n < 1 {
+		return 0, false
+	}
+	return n, true
+}
 
-	if !foundUser {
-		newVoter := Voter{
-			Name:   user,
-			Status: selectedOption,
+// rankChoice puts the option at the given rank of a ranking, removing it from
+// any other rank so that every option is ranked at most once.
+func rankChoice(ranking []string, rank int, option string) []string {
+	for len(ranking) < rank {
+		ranking = append(ranking, "")
+	}
+	for i := range ranking {
+		if ranking[i] == option {
+			ranking[i] = ""
 		}
-		pollResource.Spec.Voters = append(pollResource.Spec.Voters, newVoter)
 	}
+	ranking[rank-1] = option
+	return ranking
+}
 
-	pollResource.GetObjectMeta().SetManagedFields(nil)
-	pollBytes, _ := json.Marshal(pollResource)
-	_, err = dynamicClient.Resource(resourceId).Namespace("").Patch(ctx, pollResource.GetObjectMeta().GetName(), types.MergePatchType, pollBytes, metav1.PatchOptions{FieldManager: "slack-collector"})
-	if err != nil {
-		fmt.Println("Error patching poll resource", err)
+// castVote returns the voter updated with the selected options.
+func castVote(poll *Poll, voter Voter, actionID string, selectedOptions []string) (Voter, error) {
+	if !validSelection(poll, selectedOptions) {
+		return voter, errInvalidOption
+	}
+	switch {
+	case poll.Spec.Method == "rankedChoice":
+		rank, ok := actionRank(actionID)
+		if !ok || rank > len(pollOptions(poll.Spec.Options)) {
+			return voter, errInvalidOption
+		}
+		return Voter{Name: voter.Name, Choices: rankChoice(voter.Choices, rank, selectedOptions[0])}, nil
+	case poll.Spec.Selection == "multiple":
+		return Voter{Name: voter.Name, Choices: selectedOptions}, nil
+	default:
+		return Voter{Name: voter.Name, Status: selectedOptions[0]}, nil
 	}
-	return nil
 }
 
-// getK8sResource gets the Kubernetes resource.
-func getK8sResource(dynamicClient dynamic.Interface, ctx context.Context, pollSlackName string, resId schema.GroupVersionResource) (*Poll, error) {
+// answerQuestion returns the voter with the answer to one question of a multi-question poll.
+func answerQuestion(poll *Poll, voter Voter, questionID string, selectedOptions []string) (Voter, error) {
+	for _, question := range poll.Spec.Questions {
+		if question.ID != questionID {
+			continue
+		}
+		if len(selectedOptions) != 1 || !validOption(question.Options, selectedOptions[0]) {
+			return voter, errInvalidOption
+		}
+		answers := make(map[string]string, len(voter.Answers)+1)
+		for id, answer := range voter.Answers {
+			answers[id] = answer
+		}
+		answers[questionID] = selectedOptions[0]
+		return Voter{Name: voter.Name, Answers: answers}, nil
+	}
+	return voter, errInvalidOption
+}
 
-	res, err := dynamicClient.Resource(resId).Namespace("").
-		List(ctx, metav1.ListOptions{})
+// orderItem returns the voter with the ordered quantity of a menu item.
+func orderItem(poll *Poll, voter Voter, itemID string, selectedOptions []string) (Voter, error) {
+	for _, item := range poll.Spec.Menu {
+		if item.ID != itemID {
+			continue
+		}
+		if len(selectedOptions) != 1 {
+			return voter, errInvalidOption
+		}
+		quantity, err := strconv.ParseInt(selectedOptions[0], 10, 64)
+		if err != nil || quantity < 0 || quantity > maxQuantity {
+			return voter, errInvalidOption
+		}
+		order := make(map[string]int64, len(voter.Order)+1)
+		for id, q := range voter.Order {
+			order[id] = q
+		}
+		order[itemID] = quantity
+		return Voter{Name: voter.Name, Order: order}, nil
+	}
+	return voter, errInvalidOption
+}
 
+// checkOpen makes sure the poll accepts votes for the round. A poll past its
+// deadline that the function didn't close yet is moved to Closing. Like votes,
+// the move is conditional on the resource version the poll was read at, so a
+// conflict is retried by the caller on the latest poll.
+func checkOpen(poll *Poll, round string, dynamicClient dynamic.Interface, ctx context.Context) error {
+	if err := checkRound(poll, round); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	// The function computes the close time once the round opened, a close time
+	// older than the round is left over from the previous one.
+	if poll.Status.CloseTime == 0 || poll.Status.CloseTime < poll.Status.LastNotificationTime || now < poll.Status.CloseTime {
+		return nil
+	}
+	if !canTransition(poll.phase(), PhaseClosing) {
+		return errPollClosed
+	}
+	statusBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"resourceVersion": poll.GetObjectMeta().GetResourceVersion(),
+		},
+		"status": map[string]interface{}{
+			"done":  true,
+			"phase": PhaseClosing,
+			"phaseTimestamps": map[string]interface{}{
+				"closing": now,
+			},
+		},
+	})
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if _, err := dynamicClient.Resource(resourceId).Namespace("").Patch(ctx, poll.GetObjectMeta().GetName(), types.MergePatchType, statusBytes, metav1.PatchOptions{FieldManager: "slack-collector"}, "status"); err != nil {
+		return err
 	}
+	return errPollClosed
+}
+
+// voters returns the votes of the current round. Until the function has
+// migrated them, the votes of older polls are still read from spec.voters.
+func (p *Poll) voters() []Voter {
+	if p.Status.VotersMigrated {
+		return p.Status.Voters
+	}
+	return mergeVoters(p.Spec.Voters, p.Status.Voters)
+}
+
+// mergeVoters adds the legacy voters to the voters in status, keeping the
+// status entry of voters present in both.
+func mergeVoters(legacy, voters []Voter) []Voter {
+	merged := make([]Voter, 0, len(legacy)+len(voters))
+	seen := make(map[string]bool, len(voters))
+	for _, voter := range voters {
+		seen[voter.Name] = true
+	}
+	for _, voter := range legacy {
+		if !seen[voter.Name] {
+			merged = append(merged, voter)
+		}
+	}
+	return append(merged, voters...)
+}
+
+// voteTimeout bounds the retries of a vote that conflicted with other updates
+// of the poll status, like votes of others in the same poll, within the three
+// seconds Slack waits for the interaction to be acknowledged.
+const voteTimeout = 2500 * time.Millisecond
+
+// voteBackoff spaces the retries of a conflicting vote. Jitter spreads the
+// retries of votes cast at the same time, and the cap keeps retrying often
+// enough for everyone of a large team voting at once to be recorded.
+var voteBackoff = wait.Backoff{
+	Steps:    math.MaxInt32,
+	Duration: 5 * time.Millisecond,
+	Factor:   1.3,
+	Jitter:   1,
+	Cap:      100 * time.Millisecond,
+}
 
-	for _, item := range res.Items {
-		res := &Poll{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, res); err != nil {
-			return nil, fmt.Errorf("error converting Unstructured to Poll struct: %v", err)
+// retryOnConflict runs the update until it doesn't conflict with another
+// update of the poll, or until the vote timeout passes.
+func retryOnConflict(ctx context.Context, update func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, voteTimeout)
+	defer cancel()
+	backoff := voteBackoff
+	for {
+		err := update()
+		if !apierrors.IsConflict(err) {
+			return err
 		}
-		if res.GetObjectMeta().GetName() == pollSlackName {
-			return res, nil
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff.Step()):
 		}
 	}
+}
+
+// patchVoter records the vote of a user, as returned by the vote function, in
+// the poll status and returns the response message of the poll. The patch is
+// conditional on the resource version the vote was based on and is retried on
+// conflicts, so concurrent updates don't overwrite each other.
+func patchVoter(user, pollSlackName, round string, vote func(*Poll, Voter) (Voter, error), dynamicClient dynamic.Interface, ctx context.Context) (string, error) {
+	var response string
+	err := retryOnConflict(ctx, func() error {
+		pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
+		if err != nil {
+			return err
+		}
+		response = pollResource.Spec.Messages.Response
+		if err := checkOpen(pollResource, round, dynamicClient, ctx); err != nil {
+			return err
+		}
+		voters := pollResource.voters()
+		foundUser := false
+		for i := range voters {
+			if voters[i].Name == user {
+				voters[i], err = vote(pollResource, voters[i])
+				if err != nil {
+					return err
+				}
+				foundUser = true
+				break
+			}
+		}
+
+		if !foundUser {
+			newVoter, err := vote(pollResource, Voter{Name: user})
+			if err != nil {
+				return err
+			}
+			voters = append(voters, newVoter)
+		}
 
-	return nil, fmt.Errorf("poll resource with name %s not found", pollSlackName)
+		statusBytes, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": pollResource.GetObjectMeta().GetResourceVersion(),
+			},
+			"status": map[string]interface{}{
+				"voters": voters,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = dynamicClient.Resource(resourceId).Namespace("").Patch(ctx, pollResource.GetObjectMeta().GetName(), types.MergePatchType, statusBytes, metav1.PatchOptions{FieldManager: "slack-collector"}, "status")
+		return err
+	})
+	return response, err
+}
+
+// getK8sResource gets the poll a reference points to.
+func getK8sResource(dynamicClient dynamic.Interface, ctx context.Context, pollSlackName string, resId schema.GroupVersionResource) (*Poll, error) {
+	name, err := pollName(dynamicClient, ctx, pollSlackName)
+	if err != nil {
+		return nil, err
+	}
+	item, err := dynamicClient.Resource(resId).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	res := &Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, res); err != nil {
+		return nil, fmt.Errorf("error converting Unstructured to Poll struct: %v", err)
+	}
+	return res, nil
 }
 
 // respondMsg sends a response message to Slack.
-func respondMsg(userID string, userName string, selectedOption string, pollName string) {
+func respondMsg(userID string, userName string, text string, pollName string) {
 
 	attachment := slack.Attachment{
 		Color:      "#f9a41b",
 		CallbackID: pollName,
-		Text:       response + "\n Selected: " + selectedOption,
+		Text:       text,
 		Fields:     []slack.AttachmentField{},
 		Actions:    []slack.AttachmentAction{},
 		MarkdownIn: []string{},
@@ -191,6 +590,22 @@ func respondMsg(userID string, userName string, selectedOption string, pollName
 	fmt.Printf("message sent to user %s (%s) in channel %s\n", userName, userID, channelID)
 }
 
+// respondEphemeral replies with a message only the user can see in the
+// channel of the action, falling back to a direct message.
+func respondEphemeral(channelID, userID, userName, text, pollName string) {
+	if channelID == "" {
+		respondMsg(userID, userName, text, pollName)
+		return
+	}
+	_, err := api.PostEphemeral(channelID, userID, slack.MsgOptionText(text, false))
+	if err != nil {
+		fmt.Printf("error: %s\n", err)
+		return
+	}
+
+	fmt.Printf("ephemeral message sent to user %s (%s) in channel %s\n", userName, userID, channelID)
+}
+
 func main() {
 	ctx := context.Background()
 	config := ctrl.GetConfigOrDie()
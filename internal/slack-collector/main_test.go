@@ -0,0 +1,683 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeClient returns a fake dynamic client holding the poll. Unlike the
+// default fake, patches are atomic and rejected with a conflict when their
+// resource version is stale, like the API server does.
+func newFakeClient(poll *unstructured.Unstructured) *fake.FakeDynamicClient {
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{resourceId: "PollList"}, poll)
+	var mu sync.Mutex
+	client.PrependReactor("patch", "polls", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		patch := action.(k8stesting.PatchAction)
+		obj, err := client.Tracker().Get(resourceId, patch.GetNamespace(), patch.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		current := obj.(*unstructured.Unstructured)
+
+		precondition := unstructured.Unstructured{}
+		if err := json.Unmarshal(patch.GetPatch(), &precondition.Object); err != nil {
+			return true, nil, err
+		}
+		if rv := precondition.GetResourceVersion(); rv != "" && rv != current.GetResourceVersion() {
+			return true, nil, apierrors.NewConflict(resourceId.GroupResource(), patch.GetName(), fmt.Errorf("resource version %s is stale", rv))
+		}
+
+		currentBytes, err := json.Marshal(current.Object)
+		if err != nil {
+			return true, nil, err
+		}
+		patchedBytes, err := jsonpatch.MergePatch(currentBytes, patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		patched := &unstructured.Unstructured{}
+		if err := json.Unmarshal(patchedBytes, &patched.Object); err != nil {
+			return true, nil, err
+		}
+		version, _ := strconv.Atoi(current.GetResourceVersion())
+		patched.SetResourceVersion(strconv.Itoa(version + 1))
+		if err := client.Tracker().Update(resourceId, patched, patch.GetNamespace()); err != nil {
+			return true, nil, err
+		}
+		return true, patched, nil
+	})
+	return client
+}
+
+// slowReads delays reads of polls like a round trip to the API server does,
+// so votes cast at the same time are based on the same resource version.
+type slowReads struct {
+	dynamic.Interface
+}
+
+func (c slowReads) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return slowResource{c.Interface.Resource(resource)}
+}
+
+type slowResource struct {
+	dynamic.NamespaceableResourceInterface
+}
+
+func (r slowResource) Get(ctx context.Context, name string, options metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	u, err := r.NamespaceableResourceInterface.Get(ctx, name, options, subresources...)
+	time.Sleep(time.Millisecond)
+	return u, err
+}
+
+func TestPatchVoterConcurrent(t *testing.T) {
+	// Everyone of a large team voting within the same second.
+	const votes = 200
+
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata": map[string]interface{}{
+			"name":            "lunch",
+			"resourceVersion": "1",
+		},
+		"spec": map[string]interface{}{
+			"title": "lunch",
+		},
+		"status": map[string]interface{}{
+			"phase":                "Open",
+			"round":                "100",
+			"lastNotificationTime": int64(100),
+			"votersMigrated":       true,
+		},
+	}}
+	client := newFakeClient(poll)
+	ctx := context.Background()
+	var patches atomic.Int64
+	client.PrependReactor("patch", "polls", func(k8stesting.Action) (bool, runtime.Object, error) {
+		patches.Add(1)
+		return false, nil, nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, votes+1)
+
+	// The votes conflict with each other, and with status updates of other
+	// clients.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			status := []byte(`{"status":{"lastNotificationTime":100}}`)
+			if _, err := client.Resource(resourceId).Patch(ctx, "lunch", types.MergePatchType, status, metav1.PatchOptions{}, "status"); err != nil {
+				errs <- err
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	for i := 0; i < votes; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			_, err := patchVoter(user, "lunch", "100", func(poll *Poll, voter Voter) (Voter, error) {
+				return castVote(poll, voter, "", []string{"Yes"})
+			}, slowReads{client}, ctx)
+			if err != nil {
+				errs <- err
+			}
+		}(fmt.Sprintf("user-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("patchVoter(...): %v", err)
+	}
+
+	got, err := getK8sResource(client, ctx, "lunch", resourceId)
+	if err != nil {
+		t.Fatalf("getK8sResource(...): %v", err)
+	}
+	voted := map[string]bool{}
+	for _, voter := range got.Status.Voters {
+		voted[voter.Name] = true
+	}
+	want := map[string]bool{}
+	for i := 0; i < votes; i++ {
+		want[fmt.Sprintf("user-%d", i)] = true
+	}
+	if diff := cmp.Diff(want, voted); diff != "" {
+		t.Errorf("patchVoter(...): votes were lost: -want, +got:\n%s", diff)
+	}
+	if patches.Load() <= votes+20 {
+		t.Errorf("patchVoter(...): expected conflicting votes, got %d patches for %d updates", patches.Load(), votes+20)
+	}
+}
+
+func TestCheckOpen(t *testing.T) {
+	now := time.Now().Unix()
+	poll := func(resourceVersion string, phase Phase, lastNotificationTime, closeTime int64) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind":       "Poll",
+			"metadata": map[string]interface{}{
+				"name":            "lunch",
+				"resourceVersion": resourceVersion,
+			},
+			"status": map[string]interface{}{
+				"phase":                string(phase),
+				"lastNotificationTime": lastNotificationTime,
+				"closeTime":            closeTime,
+			},
+		}}
+	}
+
+	type want struct {
+		err      error
+		conflict bool
+		phase    Phase
+	}
+
+	cases := map[string]struct {
+		reason string
+		stored *unstructured.Unstructured
+		read   *unstructured.Unstructured
+		want   want
+	}{
+		"Open": {
+			reason: "Votes before the close time should be accepted",
+			stored: poll("1", PhaseOpen, now-60, now+60),
+			read:   poll("1", PhaseOpen, now-60, now+60),
+			want:   want{phase: PhaseOpen},
+		},
+		"PastCloseTime": {
+			reason: "A poll past its close time should be moved to Closing",
+			stored: poll("1", PhaseOpen, now-120, now-60),
+			read:   poll("1", PhaseOpen, now-120, now-60),
+			want:   want{err: errPollClosed, phase: PhaseClosing},
+		},
+		"StaleResourceVersion": {
+			reason: "A poll read before another update should not be moved to Closing, so the caller retries on the latest poll",
+			stored: poll("2", PhaseOpen, now-30, now+60),
+			read:   poll("1", PhaseOpen, now-120, now-60),
+			want:   want{conflict: true, phase: PhaseOpen},
+		},
+		"CloseTimeOfPreviousRound": {
+			reason: "A close time older than the round is left from the previous round and should not close the poll",
+			stored: poll("1", PhaseOpen, now-30, now-60),
+			read:   poll("1", PhaseOpen, now-30, now-60),
+			want:   want{phase: PhaseOpen},
+		},
+		"Closed": {
+			reason: "Votes on a poll that isn't open should be rejected without moving it",
+			stored: poll("1", PhaseResultsPosted, now-120, now-60),
+			read:   poll("1", PhaseResultsPosted, now-120, now-60),
+			want:   want{err: errPollClosed, phase: PhaseResultsPosted},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			client := newFakeClient(tc.stored)
+			ctx := context.Background()
+			read := &Poll{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(tc.read.Object, read); err != nil {
+				t.Fatal(err)
+			}
+
+			err := checkOpen(read, "", client, ctx)
+			if tc.want.conflict {
+				if !apierrors.IsConflict(err) {
+					t.Errorf("%s\ncheckOpen(...): want a conflict, got %v", tc.reason, err)
+				}
+			} else if diff := cmp.Diff(tc.want.err, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\ncheckOpen(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			got, err := getK8sResource(client, ctx, "lunch", resourceId)
+			if err != nil {
+				t.Fatalf("getK8sResource(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want.phase, got.phase()); diff != "" {
+				t.Errorf("%s\ncheckOpen(...): -want phase, +got phase:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSplitBlockID(t *testing.T) {
+	type want struct {
+		callbackID string
+		itemID     string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		blockID string
+		want    want
+	}{
+		"Poll": {
+			reason:  "The block ID of a poll should be split into the callback ID and the item ID",
+			blockID: "lunch@100/pizza",
+			want:    want{callbackID: "lunch@100", itemID: "pizza"},
+		},
+		"Claim": {
+			reason:  "The slash between the namespace and name of a claim should be kept",
+			blockID: "team-a/lunch@100/pizza",
+			want:    want{callbackID: "team-a/lunch@100", itemID: "pizza"},
+		},
+		"NoItem": {
+			reason:  "Block IDs without an item should return just the callback ID",
+			blockID: "team-a/lunch@100",
+			want:    want{callbackID: "team-a/lunch@100"},
+		},
+		"Legacy": {
+			reason:  "Block IDs of messages sent before rounds were stamped should still be split",
+			blockID: "lunch/pizza",
+			want:    want{callbackID: "lunch", itemID: "pizza"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			callbackID, itemID := splitBlockID(tc.blockID)
+			if diff := cmp.Diff(tc.want, want{callbackID: callbackID, itemID: itemID}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nsplitBlockID(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGetK8sResource(t *testing.T) {
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata": map[string]interface{}{
+			"name": "lunch-x7k2p",
+		},
+		"spec": map[string]interface{}{
+			"title": "lunch",
+		},
+	}}
+	claim := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "PollClaim",
+		"metadata": map[string]interface{}{
+			"name":      "lunch",
+			"namespace": "team-a",
+		},
+		"spec": map[string]interface{}{
+			"resourceRef": map[string]interface{}{
+				"name": "lunch-x7k2p",
+			},
+		},
+	}}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		resourceId:      "PollList",
+		claimResourceId: "PollClaimList",
+	}, poll, claim)
+
+	type want struct {
+		name string
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		ref    string
+		want   want
+	}{
+		"Poll": {
+			reason: "A poll should be found by its name",
+			ref:    "lunch-x7k2p",
+			want:   want{name: "lunch-x7k2p"},
+		},
+		"Claim": {
+			reason: "A claimed poll should be found by the namespace and name of its claim",
+			ref:    "team-a/lunch",
+			want:   want{name: "lunch-x7k2p"},
+		},
+		"OtherNamespace": {
+			reason: "A claim should not be found in another namespace",
+			ref:    "team-b/lunch",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			poll, err := getK8sResource(client, context.Background(), tc.ref, resourceId)
+			if err != nil {
+				got.err = true
+			} else {
+				got.name = poll.GetObjectMeta().GetName()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\ngetK8sResource(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestRequestAction(t *testing.T) {
+	type want struct {
+		action string
+		by     string
+		err    error
+	}
+
+	cases := map[string]struct {
+		reason     string
+		user       string
+		callbackID string
+		want       want
+	}{
+		"Organizer": {
+			reason:     "An organizer should annotate the poll with the action",
+			user:       "alice",
+			callbackID: "lunch@100",
+			want:       want{action: "close", by: "alice"},
+		},
+		"NotOrganizer": {
+			reason:     "Users who don't organize the poll should be rejected",
+			user:       "bob",
+			callbackID: "lunch@100",
+			want:       want{err: errNotOrganizer},
+		},
+		"StaleRound": {
+			reason:     "Actions on messages of an earlier round should be rejected",
+			user:       "alice",
+			callbackID: "lunch@50",
+			want:       want{err: errStaleRound},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			poll := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "kndp.io/v1alpha1",
+				"kind":       "Poll",
+				"metadata": map[string]interface{}{
+					"name": "lunch",
+				},
+				"spec": map[string]interface{}{
+					"title":      "lunch",
+					"organizers": []interface{}{"alice"},
+				},
+				"status": map[string]interface{}{
+					"phase": "Open",
+					"round": "100",
+				},
+			}}
+			client := newFakeClient(poll)
+			ctx := context.Background()
+
+			err := requestAction(tc.user, tc.callbackID, "close", client, ctx)
+			got, getErr := client.Resource(resourceId).Get(ctx, "lunch", metav1.GetOptions{})
+			if getErr != nil {
+				t.Fatalf("Get(...): %v", getErr)
+			}
+			annotations := got.GetAnnotations()
+			if diff := cmp.Diff(tc.want, want{action: annotations[actionAnnotation], by: annotations[actionByAnnotation], err: err}, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nrequestAction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestHandleEventsEndpoint(t *testing.T) {
+	vote := func(callbackID, value string) string {
+		return `{"type":"interactive_message","user":{"id":"U1","name":"alice"},"callback_id":"` + callbackID + `","channel":{"id":"D1"},` +
+			`"actions":[{"name":"actionSelect","type":"select","selected_options":[{"value":"` + value + `"}]}]}`
+	}
+	answer := func(callbackID string) string {
+		return `{"type":"interactive_message","user":{"id":"U1","name":"alice"},"callback_id":"` + callbackID + `","channel":{"id":"D1"},"trigger_id":"T1",` +
+			`"actions":[{"name":"actionAnswer","type":"button"}]}`
+	}
+	submit := func(privateMetadata, value string) string {
+		return `{"type":"view_submission","user":{"id":"U1","name":"alice"},"view":{"callback_id":"pollAnswer","private_metadata":"` + privateMetadata + `",` +
+			`"state":{"values":{"answer":{"answer":{"value":"` + value + `"}}}}}}`
+	}
+	number := map[string]interface{}{"type": "number", "min": int64(1), "max": int64(10)}
+
+	type want struct {
+		voters   []Voter
+		messages []postedMessage
+		views    []string
+		response string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		phase   string
+		answer  map[string]interface{}
+		payload string
+		want    want
+	}{
+		"Vote": {
+			reason:  "The vote should be recorded and confirmed to the voter",
+			phase:   "Open",
+			payload: vote("lunch@100", "Yes"),
+			want: want{
+				voters:   []Voter{{Name: "alice", Status: "Yes"}},
+				messages: []postedMessage{{Channel: "U1", Text: "Thanks for voting!\n Selected: Yes"}},
+			},
+		},
+		"InvalidOption": {
+			reason:  "A vote for an option the poll doesn't offer should be rejected",
+			phase:   "Open",
+			payload: vote("lunch@100", "Maybe"),
+			want: want{
+				messages: []postedMessage{{Channel: "U1", Text: `"Maybe" is not a valid choice for this poll.`}},
+			},
+		},
+		"StaleRound": {
+			reason:  "A vote on the message of an earlier round should be rejected",
+			phase:   "Open",
+			payload: vote("lunch@50", "Yes"),
+			want: want{
+				messages: []postedMessage{{Channel: "D1", User: "U1", Text: "This message is from an earlier round of the poll, please use the latest one. Your vote was not recorded.", Ephemeral: true}},
+			},
+		},
+		"Closed": {
+			reason:  "A vote on a closed poll should be rejected",
+			phase:   "Closed",
+			payload: vote("lunch@100", "Yes"),
+			want: want{
+				messages: []postedMessage{{Channel: "D1", User: "U1", Text: "This poll is closed. Your vote was not recorded.", Ephemeral: true}},
+			},
+		},
+		"OpenAnswerModal": {
+			reason:  "Clicking the answer button should open the answer modal for the round",
+			phase:   "Open",
+			answer:  number,
+			payload: answer("lunch@100"),
+			want:    want{views: []string{"pollAnswer lunch@100"}},
+		},
+		"SubmitAnswer": {
+			reason:  "A valid answer submitted through the modal should be recorded and confirmed",
+			phase:   "Open",
+			answer:  number,
+			payload: submit("lunch@100", " 7 "),
+			want: want{
+				voters:   []Voter{{Name: "alice", Answer: "7"}},
+				messages: []postedMessage{{Channel: "U1", Text: "Thanks for voting!\n Answer: 7"}},
+			},
+		},
+		"SubmitInvalidAnswer": {
+			reason:  "An invalid answer should be shown next to the input of the modal and not recorded",
+			phase:   "Open",
+			answer:  number,
+			payload: submit("lunch@100", "42"),
+			want:    want{response: `{"errors":{"answer":"The answer must be at most 10."},"response_action":"errors"}`},
+		},
+		"SubmitAnswerClosed": {
+			reason:  "An answer submitted after the poll closed should be rejected in the modal",
+			phase:   "Closed",
+			answer:  number,
+			payload: submit("lunch@100", "7"),
+			want:    want{response: `{"errors":{"answer":"This poll is closed."},"response_action":"errors"}`},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newFakeSlack()
+			defer srv.Close()
+			srv.use(t)
+
+			poll := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "kndp.io/v1alpha1",
+				"kind":       "Poll",
+				"metadata": map[string]interface{}{
+					"name":            "lunch",
+					"resourceVersion": "1",
+				},
+				"spec": map[string]interface{}{
+					"title": "lunch",
+					"messages": map[string]interface{}{
+						"response": "Thanks for voting!",
+					},
+				},
+				"status": map[string]interface{}{
+					"phase":                tc.phase,
+					"round":                "100",
+					"lastNotificationTime": int64(100),
+					"votersMigrated":       true,
+				},
+			}}
+			if tc.answer != nil {
+				poll.Object["spec"].(map[string]interface{})["answer"] = tc.answer
+			}
+			client := newFakeClient(poll)
+			ctx := context.Background()
+
+			r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(url.Values{"payload": {tc.payload}}.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			handleEventsEndpoint(w, r, client, ctx)
+
+			got, err := getK8sResource(client, ctx, "lunch", resourceId)
+			if err != nil {
+				t.Fatalf("getK8sResource(...): %v", err)
+			}
+			messages := []postedMessage{}
+			for _, m := range srv.posted() {
+				// Only the text of the reply is compared.
+				text := m.Text
+				if len(m.Attachments) > 0 {
+					text = m.Attachments[0].Text
+				}
+				messages = append(messages, postedMessage{Channel: m.Channel, User: m.User, Text: text, Ephemeral: m.Ephemeral})
+			}
+			views := []string{}
+			for _, v := range srv.opened() {
+				views = append(views, v.View.CallbackID+" "+v.View.PrivateMetadata)
+			}
+			response := strings.TrimSpace(w.Body.String())
+			if diff := cmp.Diff(tc.want, want{voters: got.Status.Voters, messages: messages, views: views, response: response}, cmp.AllowUnexported(want{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%s\nhandleEventsEndpoint(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// TestVotePatches checks the status the collector writes when recording votes
+// against the one the function is tested with, see TestPollRound.
+func TestVotePatches(t *testing.T) {
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata": map[string]interface{}{
+			"name":            "lunch",
+			"resourceVersion": "1",
+		},
+		"spec": map[string]interface{}{
+			"title": "lunch",
+		},
+		"status": map[string]interface{}{
+			"votersMigrated": true,
+		},
+	}}
+	client := newFakeClient(poll)
+	ctx := context.Background()
+	// The notifier opens the round.
+	if _, err := client.Resource(resourceId).Patch(ctx, "lunch", types.MergePatchType, readRoundFixture(t, "open.json"), metav1.PatchOptions{}, "status"); err != nil {
+		t.Fatal(err)
+	}
+	patches := []map[string]interface{}{}
+	client.PrependReactor("patch", "polls", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := map[string]interface{}{}
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			t.Error(err)
+		}
+		// The resource version is the precondition of the patch, not part
+		// of the status.
+		delete(patch, "metadata")
+		patches = append(patches, patch)
+		return false, nil, nil
+	})
+
+	round := strconv.Itoa(roundFixtureTime)
+	for _, v := range []struct{ user, choice string }{{"alice", "Yes"}, {"bob", "No"}} {
+		_, err := patchVoter(v.user, "lunch", round, func(poll *Poll, voter Voter) (Voter, error) {
+			return castVote(poll, voter, "", []string{v.choice})
+		}, client, ctx)
+		if err != nil {
+			t.Fatalf("patchVoter(...): %v", err)
+		}
+	}
+
+	want := []map[string]interface{}{}
+	for _, name := range []string{"vote-alice.json", "vote-bob.json"} {
+		patch := map[string]interface{}{}
+		if err := json.Unmarshal(readRoundFixture(t, name), &patch); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, patch)
+	}
+	if diff := cmp.Diff(want, patches); diff != "" {
+		t.Errorf("patchVoter(...): -want, +got:\n%s", diff)
+	}
+}
+
+// roundFixtureTime is the time the round of the fixtures shared with the
+// function and the notifier opened at.
+const roundFixtureTime = 1734951600
+
+// readRoundFixture reads a status patch of the round the function, the
+// notifier and the collector are tested with.
+func readRoundFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "testdata", "round", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	answerCallbackID = "pollAnswer"
+	answerBlockID    = "answer"
+	answerActionID   = "answer"
+)
+
+// openAnswerModal opens a modal asking for a free-text or numeric answer.
+// The callback ID of the message is kept in the modal to check the round on
+// submission.
+func openAnswerModal(triggerID, callbackID string, dynamicClient dynamic.Interface, ctx context.Context) error {
+	pollSlackName, round := splitCallbackID(callbackID)
+	pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
+	if err != nil {
+		return err
+	}
+	if err := checkRound(pollResource, round); err != nil {
+		return err
+	}
+	answer := pollResource.Spec.Answer
+
+	var element slack.BlockElement
+	if answer.Type == "number" {
+		number := slack.NewNumberInputBlockElement(nil, answerActionID, true)
+		if answer.Min != nil {
+			number.MinValue = strconv.FormatFloat(*answer.Min, 'f', -1, 64)
+		}
+		if answer.Max != nil {
+			number.MaxValue = strconv.FormatFloat(*answer.Max, 'f', -1, 64)
+		}
+		element = number
+	} else {
+		text := slack.NewPlainTextInputBlockElement(nil, answerActionID)
+		text.Multiline = true
+		element = text
+	}
+
+	modal := slack.ModalViewRequest{
+		Type:            slack.VTModal,
+		CallbackID:      answerCallbackID,
+		PrivateMetadata: callbackID,
+		Title:           slack.NewTextBlockObject(slack.PlainTextType, modalTitle(pollResource.Spec.Title), false, false),
+		Submit:          slack.NewTextBlockObject(slack.PlainTextType, "Submit", false, false),
+		Close:           slack.NewTextBlockObject(slack.PlainTextType, "Cancel", false, false),
+		Blocks: slack.Blocks{BlockSet: []slack.Block{
+			slack.NewInputBlock(answerBlockID, slack.NewTextBlockObject(slack.PlainTextType, pollResource.Spec.Messages.Question, false, false), nil, element),
+		}},
+	}
+	_, err = api.OpenView(triggerID, modal)
+	return err
+}
+
+// modalTitle shortens the poll title to the 24 characters Slack allows for modal titles.
+func modalTitle(title string) string {
+	if title == "" {
+		return "Poll"
+	}
+	runes := []rune(title)
+	if len(runes) > 24 {
+		return string(runes[:24])
+	}
+	return title
+}
+
+// answerError checks a free-text or numeric answer against the poll answer type
+// and returns the message shown to the user, or an empty string if it's valid.
+func answerError(answer Answer, value string) string {
+	if value == "" {
+		return "Please enter an answer."
+	}
+	if answer.Type != "number" {
+		return ""
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return "Please enter a number."
+	}
+	if answer.Min != nil && n < *answer.Min {
+		return "The answer must be at least " + strconv.FormatFloat(*answer.Min, 'f', -1, 64) + "."
+	}
+	if answer.Max != nil && n > *answer.Max {
+		return "The answer must be at most " + strconv.FormatFloat(*answer.Max, 'f', -1, 64) + "."
+	}
+	return ""
+}
+
+// handleViewSubmission validates and records the answer submitted through the modal.
+// Validation errors and answers for a closed or earlier round are returned to
+// Slack so they are shown next to the input.
+func handleViewSubmission(w http.ResponseWriter, data SelectedOptionValue, dynamicClient dynamic.Interface, ctx context.Context) {
+	if data.View.CallbackID != answerCallbackID {
+		return
+	}
+	pollSlackName, round := splitCallbackID(data.View.PrivateMetadata)
+	value := strings.TrimSpace(data.View.State.Values[answerBlockID][answerActionID].Value)
+
+	var invalid string
+	response, err := patchVoter(data.User.Name, pollSlackName, round, func(poll *Poll, voter Voter) (Voter, error) {
+		if invalid = answerError(poll.Spec.Answer, value); invalid != "" {
+			return voter, errInvalidOption
+		}
+		return Voter{Name: voter.Name, Answer: value}, nil
+	}, dynamicClient, ctx)
+	if text := roundError(err); text != "" {
+		invalid = text
+	}
+	if invalid != "" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{
+			"response_action": "errors",
+			"errors":          map[string]string{answerBlockID: invalid},
+		}); err != nil {
+			fmt.Println("Error encoding view submission response:", err)
+		}
+		return
+	}
+	if err != nil {
+		fmt.Println("Error patching Voter answer:", err)
+		respondMsg(data.User.ID, data.User.Name, "Your answer could not be recorded, please try again.", pollSlackName)
+		return
+	}
+	respondMsg(data.User.ID, data.User.Name, response+"\n Answer: "+value, pollSlackName)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAnswerError(t *testing.T) {
+	min, max := 1.0, 10.5
+
+	type args struct {
+		answer Answer
+		value  string
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   string
+	}{
+		"Empty": {
+			reason: "An empty answer should be rejected",
+			args:   args{answer: Answer{Type: "text"}, value: ""},
+			want:   "Please enter an answer.",
+		},
+		"Text": {
+			reason: "Any text should be a valid free-text answer",
+			args:   args{answer: Answer{Type: "text"}, value: "pizza, no olives"},
+			want:   "",
+		},
+		"NotANumber": {
+			reason: "A non-numeric answer to a numeric question should be rejected",
+			args:   args{answer: Answer{Type: "number"}, value: "twelve"},
+			want:   "Please enter a number.",
+		},
+		"BelowMin": {
+			reason: "A number below the minimum should be rejected",
+			args:   args{answer: Answer{Type: "number", Min: &min, Max: &max}, value: "0.5"},
+			want:   "The answer must be at least 1.",
+		},
+		"AboveMax": {
+			reason: "A number above the maximum should be rejected",
+			args:   args{answer: Answer{Type: "number", Min: &min, Max: &max}, value: "11"},
+			want:   "The answer must be at most 10.5.",
+		},
+		"InRange": {
+			reason: "A number within the bounds should be accepted, bounds included",
+			args:   args{answer: Answer{Type: "number", Min: &min, Max: &max}, value: "10.5"},
+			want:   "",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := answerError(tc.args.answer, tc.args.value)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nanswerError(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// Annotations the function reads organizer actions from.
+const (
+	actionAnnotation   = "poll.kndp.io/action"
+	actionIDAnnotation = "poll.kndp.io/action-id"
+	actionByAnnotation = "poll.kndp.io/action-by"
+)
+
+// organizerActions maps the organizer buttons to the actions they request.
+var organizerActions = map[string]string{
+	"organizerClose":  "close",
+	"organizerExtend": "extend",
+	"organizerReopen": "reopen",
+	"organizerCancel": "cancel",
+}
+
+// organizerReplies are the messages confirming a requested action.
+var organizerReplies = map[string]string{
+	"close":  "The poll will close and post its results shortly.",
+	"extend": "The poll will be extended by 15 minutes.",
+	"reopen": "The poll will be reopened shortly.",
+	"cancel": "The round will be cancelled without posting results.",
+}
+
+// errNotOrganizer is returned when a user who doesn't organize the poll
+// clicks an organizer button.
+var errNotOrganizer = errors.New("user is not an organizer of the poll")
+
+// isOrganizer checks if the user organizes the poll.
+func isOrganizer(poll *Poll, user string) bool {
+	for _, organizer := range poll.Spec.Organizers {
+		if organizer == user {
+			return true
+		}
+	}
+	return false
+}
+
+// requestAction annotates the poll with the action of an organizer, which the
+// function applies and records in the poll status.
+func requestAction(user, callbackID, action string, dynamicClient dynamic.Interface, ctx context.Context) error {
+	pollSlackName, round := splitCallbackID(callbackID)
+	pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
+	if err != nil {
+		return err
+	}
+	if !isOrganizer(pollResource, user) {
+		return errNotOrganizer
+	}
+	if pollResource.Status.Round != "" && round != pollResource.Status.Round {
+		return errStaleRound
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{
+				actionAnnotation:   action,
+				actionIDAnnotation: strconv.FormatInt(time.Now().UnixNano(), 10),
+				actionByAnnotation: user,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = dynamicClient.Resource(resourceId).Patch(ctx, pollResource.GetObjectMeta().GetName(), types.MergePatchType, patch, metav1.PatchOptions{FieldManager: "slack-collector"})
+	return err
+}
+
+// handleOrganizerAction handles a click on one of the organizer buttons.
+func handleOrganizerAction(data SelectedOptionValue, action string, dynamicClient dynamic.Interface, ctx context.Context) {
+	pollSlackName, _ := splitCallbackID(data.CallbackID)
+	err := requestAction(data.User.Name, data.CallbackID, action, dynamicClient, ctx)
+	switch {
+	case errors.Is(err, errNotOrganizer):
+		respondEphemeral(data.Channel.ID, data.User.ID, data.User.Name, "Only the organizers of this poll can do that.", pollSlackName)
+	case errors.Is(err, errStaleRound):
+		respondEphemeral(data.Channel.ID, data.User.ID, data.User.Name, "This message is from an earlier round of the poll.", pollSlackName)
+	case err != nil:
+		fmt.Println("Error requesting action", action, "for", pollSlackName, ":", err)
+		respondEphemeral(data.Channel.ID, data.User.ID, data.User.Name, "The action could not be requested, please try again.", pollSlackName)
+	default:
+		fmt.Println(data.User.Name, "requested action", action, "for", pollSlackName)
+		respondEphemeral(data.Channel.ID, data.User.ID, data.User.Name, organizerReplies[action], pollSlackName)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+)
+
+// Amount is a sum of money in a currency.
+type Amount struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// LedgerEntry is the share of a single voter.
+type LedgerEntry struct {
+	Name   string   `json:"name"`
+	Amount []Amount `json:"amount"`
+	Paid   bool     `json:"paid"`
+	PaidAt int64    `json:"paidAt,omitempty"`
+}
+
+// Ledger tracks the payments of a single round.
+type Ledger struct {
+	CreatedAt  int64         `json:"createdAt"`
+	Entries    []LedgerEntry `json:"entries"`
+	RemindedAt int64         `json:"remindedAt,omitempty"`
+	SettledAt  int64         `json:"settledAt,omitempty"`
+}
+
+// errNothingToPay is returned when a user without a share marks it as paid.
+var errNothingToPay = errors.New("user has nothing to pay")
+
+// markPaid marks the share of the user in the poll ledger as paid. Like votes,
+// the patch is conditional on the resource version of the ledger it's based
+// on and retried on conflicts, so payments marked at the same time are all
+// recorded.
+func markPaid(user, pollSlackName string, dynamicClient dynamic.Interface, ctx context.Context) error {
+	return retryOnConflict(ctx, func() error {
+		pollResource, err := getK8sResource(dynamicClient, ctx, pollSlackName, resourceId)
+		if err != nil {
+			return err
+		}
+		ledger := pollResource.Status.Ledger
+		if ledger == nil || ledger.SettledAt != 0 {
+			return errNothingToPay
+		}
+		found := false
+		for i := range ledger.Entries {
+			if ledger.Entries[i].Name == user {
+				ledger.Entries[i].Paid = true
+				ledger.Entries[i].PaidAt = time.Now().Unix()
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errNothingToPay
+		}
+
+		statusBytes, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"resourceVersion": pollResource.GetObjectMeta().GetResourceVersion(),
+			},
+			"status": map[string]interface{}{
+				"ledger": ledger,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		_, err = dynamicClient.Resource(resourceId).Namespace("").Patch(ctx, pollResource.GetObjectMeta().GetName(), types.MergePatchType, statusBytes, metav1.PatchOptions{FieldManager: "slack-collector"}, "status")
+		return err
+	})
+}
+
+// handlePaid handles a click on the "Mark as paid" button.
+func handlePaid(userID, user, pollSlackName string, dynamicClient dynamic.Interface, ctx context.Context) {
+	err := markPaid(user, pollSlackName, dynamicClient, ctx)
+	switch {
+	case errors.Is(err, errNothingToPay):
+		respondMsg(userID, user, "You have nothing to pay for this order.", pollSlackName)
+	case err != nil:
+		fmt.Println("Error marking payment:", err)
+	default:
+		respondMsg(userID, user, "Thanks, your payment has been recorded.", pollSlackName)
+	}
+}
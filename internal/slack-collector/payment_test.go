@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestMarkPaidConcurrent(t *testing.T) {
+	const payers = 20
+
+	entries := []interface{}{}
+	want := map[string]bool{}
+	for i := 0; i < payers; i++ {
+		name := fmt.Sprintf("user-%d", i)
+		entries = append(entries, map[string]interface{}{
+			"name":   name,
+			"amount": []interface{}{map[string]interface{}{"value": 12.5, "currency": "EUR"}},
+			"paid":   false,
+		})
+		want[name] = true
+	}
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata": map[string]interface{}{
+			"name":            "lunch",
+			"resourceVersion": "1",
+		},
+		"spec": map[string]interface{}{
+			"title": "lunch",
+		},
+		"status": map[string]interface{}{
+			"ledger": map[string]interface{}{
+				"createdAt": int64(100),
+				"entries":   entries,
+			},
+		},
+	}}
+	client := newFakeClient(poll)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, payers)
+	for i := 0; i < payers; i++ {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			if err := markPaid(user, "lunch", slowReads{client}, ctx); err != nil {
+				errs <- err
+			}
+		}(fmt.Sprintf("user-%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("markPaid(...): %v", err)
+	}
+
+	got, err := getK8sResource(client, ctx, "lunch", resourceId)
+	if err != nil {
+		t.Fatalf("getK8sResource(...): %v", err)
+	}
+	paid := map[string]bool{}
+	for _, entry := range got.Status.Ledger.Entries {
+		paid[entry.Name] = entry.Paid
+	}
+	if diff := cmp.Diff(want, paid); diff != "" {
+		t.Errorf("markPaid(...): payments were lost: -want, +got:\n%s", diff)
+	}
+}
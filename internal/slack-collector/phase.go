@@ -0,0 +1,62 @@
+package main
+
+// Phase is a step in the lifecycle of a poll round.
+type Phase string
+
+// Poll phases. A round goes from Open through Closing and Closed to
+// ResultsPosted, after which the next round opens it again. Organizers can
+// cancel a round before its results are posted.
+const (
+	PhasePending       Phase = "Pending"
+	PhaseOpen          Phase = "Open"
+	PhaseClosing       Phase = "Closing"
+	PhaseClosed        Phase = "Closed"
+	PhaseResultsPosted Phase = "ResultsPosted"
+	PhaseCancelled     Phase = "Cancelled"
+)
+
+// transitions lists the phases each phase can move to. The phases are copied
+// from the function, TestPhaseCopies in internal/slackchannel keeps them equal.
+var transitions = map[Phase][]Phase{
+	PhasePending:       {PhaseOpen},
+	PhaseOpen:          {PhaseClosing, PhaseCancelled},
+	PhaseClosing:       {PhaseClosed, PhaseCancelled},
+	PhaseClosed:        {PhaseResultsPosted, PhaseOpen, PhaseCancelled},
+	PhaseResultsPosted: {PhaseOpen},
+	PhaseCancelled:     {PhaseOpen},
+}
+
+// PhaseTimestamps records when the poll last entered each phase.
+type PhaseTimestamps struct {
+	Pending       int64 `json:"pending,omitempty"`
+	Open          int64 `json:"open,omitempty"`
+	Closing       int64 `json:"closing,omitempty"`
+	Closed        int64 `json:"closed,omitempty"`
+	ResultsPosted int64 `json:"resultsPosted,omitempty"`
+	Cancelled     int64 `json:"cancelled,omitempty"`
+}
+
+// canTransition checks if a poll can move from one phase to another.
+func canTransition(from, to Phase) bool {
+	for _, p := range transitions[from] {
+		if p == to {
+			return true
+		}
+	}
+	return false
+}
+
+// phase returns the phase of the poll, deriving it from the done flag and the
+// last notification time for polls created before phases existed.
+func (p *Poll) phase() Phase {
+	switch {
+	case p.Status.Phase != "":
+		return p.Status.Phase
+	case p.Status.LastNotificationTime == 0:
+		return PhasePending
+	case p.Status.Done:
+		return PhaseResultsPosted
+	default:
+		return PhaseOpen
+	}
+}
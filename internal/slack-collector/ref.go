@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// claimResourceId is the namespaced claim teams create polls with.
+var claimResourceId = schema.GroupVersionResource{
+	Group:    "kndp.io",
+	Version:  "v1alpha1",
+	Resource: "pollclaims",
+}
+
+// splitBlockID returns the callback ID and the question or menu item ID of a
+// block ID. The reference of claimed polls contains a slash itself, so block
+// IDs are split after the round.
+func splitBlockID(blockID string) (string, string) {
+	ref, rest, found := strings.Cut(blockID, roundSeparator)
+	if !found {
+		callbackID, itemID, _ := strings.Cut(blockID, "/")
+		return callbackID, itemID
+	}
+	round, itemID, _ := strings.Cut(rest, "/")
+	return ref + roundSeparator + round, itemID
+}
+
+// pollName returns the name of the poll a reference points to. Claimed polls
+// are referenced by the namespace and name of their claim, which is bound to
+// the poll.
+func pollName(dynamicClient dynamic.Interface, ctx context.Context, ref string) (string, error) {
+	namespace, claimName, claimed := strings.Cut(ref, "/")
+	if !claimed {
+		return ref, nil
+	}
+	claim, err := dynamicClient.Resource(claimResourceId).Namespace(namespace).Get(ctx, claimName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	name, _, err := unstructured.NestedString(claim.Object, "spec", "resourceRef", "name")
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", fmt.Errorf("poll claim %s is not bound to a poll yet", ref)
+	}
+	return name, nil
+}
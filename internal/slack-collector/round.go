@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"strings"
+)
+
+// roundSeparator separates the poll reference from the round in callback and
+// block IDs. Kubernetes names can't contain it.
+const roundSeparator = "@"
+
+// errStaleRound is returned when a vote is cast on a message of an earlier round.
+var errStaleRound = errors.New("vote belongs to an earlier round of the poll")
+
+// splitCallbackID returns the poll reference and the round of a callback ID.
+// Messages sent before rounds were stamped carry just the poll reference.
+func splitCallbackID(callbackID string) (string, string) {
+	ref, round, _ := strings.Cut(callbackID, roundSeparator)
+	return ref, round
+}
+
+// checkRound makes sure the vote belongs to the current round and that the
+// round is still open. Polls that were notified before rounds were stamped
+// accept votes from any message.
+func checkRound(poll *Poll, round string) error {
+	if poll.Status.Round != "" && round != poll.Status.Round {
+		return errStaleRound
+	}
+	if poll.phase() != PhaseOpen {
+		return errPollClosed
+	}
+	return nil
+}
+
+// roundError returns the message explaining why a vote was rejected, or an
+// empty string if the error isn't about the round.
+func roundError(err error) string {
+	switch {
+	case errors.Is(err, errStaleRound):
+		return "This message is from an earlier round of the poll, please use the latest one."
+	case errors.Is(err, errPollClosed):
+		return "This poll is closed."
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// dateLayout is the layout of excluded dates.
+const dateLayout = "2006-01-02"
+
+// defaultICSKey is the ConfigMap key read when the reference has none.
+const defaultICSKey = "calendar.ics"
+
+// maxSkippedRounds is the number of skipped rounds kept in status.
+const maxSkippedRounds = 10
+
+var (
+	configMapResourceId = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	calendarResourceId  = schema.GroupVersionResource{Group: "kndp.io", Version: "v1alpha1", Resource: "pollcalendars"}
+)
+
+// ConfigMapRef references a key of a ConfigMap holding an ICS calendar.
+type ConfigMapRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+}
+
+// CalendarRef references a cluster scoped PollCalendar.
+type CalendarRef struct {
+	Name string `json:"name"`
+}
+
+// Calendar lists the days on which the poll doesn't run. Dates are in the
+// time zone of the poll.
+type Calendar struct {
+	ExcludedDates []string      `json:"excludedDates,omitempty"`
+	ConfigMapRef  *ConfigMapRef `json:"configMapRef,omitempty"`
+	CalendarRef   *CalendarRef  `json:"calendarRef,omitempty"`
+}
+
+// SkippedRound records a scheduled round the notifier skipped.
+type SkippedRound struct {
+	Date   string `json:"date"`
+	Time   int64  `json:"time"`
+	Source string `json:"source,omitempty"`
+}
+
+// pollLocation returns the time zone of the poll schedule, which the function
+// passes to the CronJob.
+func pollLocation() *time.Location {
+	loc, err := time.LoadLocation(os.Getenv("POLL_TIME_ZONE"))
+	if err != nil {
+		fmt.Println("error loading time zone, using UTC", err)
+		return time.UTC
+	}
+	return loc
+}
+
+// icsDate parses the date of an ICS DATE or DATE-TIME value. It also reports
+// whether the value is at the start of the day.
+func icsDate(value string) (time.Time, bool, error) {
+	if len(value) < 8 {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q", value)
+	}
+	_, clock, hasTime := strings.Cut(value, "T")
+	return date, !hasTime || strings.HasPrefix(clock, "000000"), nil
+}
+
+// parseICS returns the dates covered by the events of an ICS calendar. Like
+// in ICS, the end of an event is exclusive when it's at midnight. Recurring
+// events are not expanded.
+func parseICS(data string) ([]string, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	dates := []string{}
+	var start, end string
+	for _, line := range strings.Split(data, "\n") {
+		name, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		property, _, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(property) {
+		case "BEGIN":
+			start, end = "", ""
+		case "DTSTART":
+			start = value
+		case "DTEND":
+			end = value
+		case "END":
+			if !strings.EqualFold(value, "VEVENT") || start == "" {
+				continue
+			}
+			first, _, err := icsDate(start)
+			if err != nil {
+				return nil, err
+			}
+			last := first
+			if end != "" {
+				date, midnight, err := icsDate(end)
+				if err != nil {
+					return nil, err
+				}
+				if midnight {
+					date = date.AddDate(0, 0, -1)
+				}
+				if date.After(last) {
+					last = date
+				}
+			}
+			// Events longer than a year are cut, no poll runs that rarely.
+			for d, i := first, 0; !d.After(last) && i <= 366; d, i = d.AddDate(0, 0, 1), i+1 {
+				dates = append(dates, d.Format(dateLayout))
+			}
+		}
+	}
+	return dates, nil
+}
+
+// icsDates reads the dates of the ICS calendar in the referenced ConfigMap.
+func icsDates(ref *ConfigMapRef, client dynamic.Interface, ctx context.Context) ([]string, error) {
+	configMap, err := client.Resource(configMapResourceId).Namespace(ref.Namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	key := ref.Key
+	if key == "" {
+		key = defaultICSKey
+	}
+	data, found, err := unstructured.NestedString(configMap.Object, "data", key)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("configmap %s/%s has no key %s", ref.Namespace, ref.Name, key)
+	}
+	return parseICS(data)
+}
+
+// resolveCalendar returns the dates the poll doesn't run on, mapped to where
+// they are excluded. The dates of the poll, its ICS ConfigMap and its
+// PollCalendar are combined.
+func resolveCalendar(calendar Calendar, client dynamic.Interface, ctx context.Context) (map[string]string, error) {
+	excluded := map[string]string{}
+	add := func(dates []string, source string) {
+		for _, date := range dates {
+			if _, ok := excluded[date]; !ok {
+				excluded[date] = source
+			}
+		}
+	}
+	add(calendar.ExcludedDates, "poll")
+	if ref := calendar.ConfigMapRef; ref != nil {
+		dates, err := icsDates(ref, client, ctx)
+		if err != nil {
+			return excluded, fmt.Errorf("cannot read calendar from configmap %s/%s: %w", ref.Namespace, ref.Name, err)
+		}
+		add(dates, "configmap/"+ref.Name)
+	}
+	if ref := calendar.CalendarRef; ref != nil {
+		item, err := client.Resource(calendarResourceId).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return excluded, fmt.Errorf("cannot get pollcalendar %s: %w", ref.Name, err)
+		}
+		dates, _, _ := unstructured.NestedStringSlice(item.Object, "spec", "excludedDates")
+		add(dates, "pollcalendar/"+ref.Name)
+		namespace, _, _ := unstructured.NestedString(item.Object, "spec", "configMapRef", "namespace")
+		name, _, _ := unstructured.NestedString(item.Object, "spec", "configMapRef", "name")
+		key, _, _ := unstructured.NestedString(item.Object, "spec", "configMapRef", "key")
+		if name != "" {
+			dates, err := icsDates(&ConfigMapRef{Namespace: namespace, Name: name, Key: key}, client, ctx)
+			if err != nil {
+				return excluded, fmt.Errorf("cannot read calendar of pollcalendar %s: %w", ref.Name, err)
+			}
+			add(dates, "pollcalendar/"+ref.Name)
+		}
+	}
+	return excluded, nil
+}
+
+// upcomingDates returns the sorted excluded dates from today on, up to a year
+// ahead, for the function to compute the next run.
+func upcomingDates(excluded map[string]string, today time.Time) []string {
+	from := today.Format(dateLayout)
+	until := today.AddDate(1, 0, 0).Format(dateLayout)
+	dates := []string{}
+	for date := range excluded {
+		if date >= from && date <= until {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// skipRound records a round skipped because of the calendar in status.
+func skipRound(poll *Poll, skipped SkippedRound, upcoming []string, client dynamic.Interface, ctx context.Context) error {
+	skippedRounds := append(poll.Status.SkippedRounds, skipped)
+	if len(skippedRounds) > maxSkippedRounds {
+		skippedRounds = skippedRounds[len(skippedRounds)-maxSkippedRounds:]
+	}
+	return patchStatus(poll, map[string]interface{}{
+		"excludedDates": upcoming,
+		"skippedRounds": skippedRounds,
+	}, client, ctx)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20241225\r\n" +
+	"DTEND;VALUE=DATE:20241227\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Office closed for the\r\n" +
+	"  team offsite\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20241230T090000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20241230T170000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20250101\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	type want struct {
+		dates []string
+		err   bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		data   string
+		want   want
+	}{
+		"Events": {
+			reason: "All days of all-day events, timed events and events without an end should be excluded",
+			data:   holidays,
+			want:   want{dates: []string{"2024-12-25", "2024-12-26", "2024-12-30", "2025-01-01"}},
+		},
+		"InvalidDate": {
+			reason: "Events with an invalid date should be rejected",
+			data:   "BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\n",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dates, err := parseICS(tc.data)
+			if diff := cmp.Diff(tc.want, want{dates: dates, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nparseICS(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolveCalendar(t *testing.T) {
+	configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "holidays",
+			"namespace": "default",
+		},
+		"data": map[string]interface{}{
+			"calendar.ics": holidays,
+		},
+	}}
+	calendar := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "PollCalendar",
+		"metadata": map[string]interface{}{
+			"name": "office",
+		},
+		"spec": map[string]interface{}{
+			"excludedDates": []interface{}{"2024-12-24", "2024-12-25"},
+		},
+	}}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+		configMapResourceId: "ConfigMapList",
+		calendarResourceId:  "PollCalendarList",
+	}, configMap, calendar)
+
+	type want struct {
+		excluded map[string]string
+		err      bool
+	}
+
+	cases := map[string]struct {
+		reason   string
+		calendar Calendar
+		want     want
+	}{
+		"Combined": {
+			reason: "Dates of the poll, the ICS ConfigMap and the PollCalendar should be combined, the first source wins",
+			calendar: Calendar{
+				ExcludedDates: []string{"2024-12-31"},
+				ConfigMapRef:  &ConfigMapRef{Namespace: "default", Name: "holidays"},
+				CalendarRef:   &CalendarRef{Name: "office"},
+			},
+			want: want{excluded: map[string]string{
+				"2024-12-31": "poll",
+				"2024-12-25": "configmap/holidays",
+				"2024-12-26": "configmap/holidays",
+				"2024-12-30": "configmap/holidays",
+				"2025-01-01": "configmap/holidays",
+				"2024-12-24": "pollcalendar/office",
+			}},
+		},
+		"MissingConfigMap": {
+			reason: "A missing ConfigMap should return an error along with the dates resolved so far",
+			calendar: Calendar{
+				ExcludedDates: []string{"2024-12-31"},
+				ConfigMapRef:  &ConfigMapRef{Namespace: "team-a", Name: "holidays"},
+			},
+			want: want{excluded: map[string]string{"2024-12-31": "poll"}, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			excluded, err := resolveCalendar(tc.calendar, client, context.Background())
+			if diff := cmp.Diff(tc.want, want{excluded: excluded, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nresolveCalendar(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestUpcomingDates(t *testing.T) {
+	excluded := map[string]string{"2024-12-24": "poll", "2024-12-25": "poll", "2025-01-01": "poll", "2026-01-01": "poll"}
+	today := time.Date(2024, 12, 25, 11, 0, 0, 0, time.UTC)
+
+	got := upcomingDates(excluded, today)
+	want := []string{"2024-12-25", "2025-01-01"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("upcomingDates(...): -want, +got:\n%s", diff)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nlopes/slack"
+)
+
+// fakeSlack is a fake Slack Web API that records the messages posted to it
+// and serves the given conversation members. It mirrors the slacktest
+// package of the function, which this module can't import.
+type fakeSlack struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	members  []slack.User
+	messages []postedMessage
+}
+
+// postedMessage is a message posted to the fake Slack.
+type postedMessage struct {
+	Channel     string
+	Text        string
+	Attachments []slack.Attachment
+	Blocks      string
+}
+
+func newFakeSlack(members ...slack.User) *fakeSlack {
+	s := &fakeSlack{members: members}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// use points the Slack client to the fake Slack for the test, through
+// SLACK_API_URL like in a deployment.
+func (s *fakeSlack) use(t *testing.T) {
+	t.Setenv("SLACK_API_URL", s.URL+"/")
+	previous := api
+	api = newSlackClient("xoxb-test")
+	t.Cleanup(func() { api = previous })
+}
+
+// messagesTo returns the messages posted to the channel or user.
+func (s *fakeSlack) messagesTo(channel string) []postedMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	messages := []postedMessage{}
+	for _, m := range s.messages {
+		if m.Channel == channel {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+func (s *fakeSlack) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var body interface{}
+	switch r.URL.Path[1:] {
+	case "conversations.members":
+		ids := make([]string, 0, len(s.members))
+		for _, member := range s.members {
+			ids = append(ids, member.ID)
+		}
+		body = map[string]interface{}{"ok": true, "members": ids}
+	case "users.info":
+		body = map[string]interface{}{"ok": false, "error": "user_not_found"}
+		for _, member := range s.members {
+			if member.ID == r.FormValue("user") {
+				body = map[string]interface{}{"ok": true, "user": member}
+			}
+		}
+	case "chat.postMessage":
+		m := postedMessage{Channel: r.FormValue("channel"), Text: r.FormValue("text"), Blocks: r.FormValue("blocks")}
+		if attachments := r.FormValue("attachments"); attachments != "" {
+			_ = json.Unmarshal([]byte(attachments), &m.Attachments)
+		}
+		s.messages = append(s.messages, m)
+		body = map[string]interface{}{"ok": true, "channel": m.Channel, "ts": fmt.Sprintf("%d.000000", len(s.messages))}
+	default:
+		body = map[string]interface{}{"ok": false, "error": "unknown_method"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -5,13 +5,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	// Embed the IANA database so loading the poll time zone does not depend on
+	// the zoneinfo of the image.
+	_ "time/tzdata"
+
 	"github.com/nlopes/slack"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/dynamic"
 	ctrl "sigs.k8s.io/controller-runtime/pkg/client/config"
 )
@@ -22,19 +29,72 @@ var (
 	pollName           = os.Getenv("POLL_NAME")
 	pollTitle          = os.Getenv("POLL_TITLE")
 	slackNotifyMessage = os.Getenv("SLACK_NOTIFY_MESSAGE")
+	// callbackID is the poll name stamped with the current round.
+	callbackID = pollName
+	// api is the Slack Web API the poll is sent with.
+	api Messenger = newSlackClient(token)
 )
 
+// resourceId identifies the Poll composite resources.
+var resourceId = schema.GroupVersionResource{
+	Group:    "kndp.io",
+	Version:  "v1alpha1",
+	Resource: "polls",
+}
+
 // Voter represents the structure of an Voter reference.
 type Voter struct {
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Choices []string          `json:"choices,omitempty"`
+	Answer  string            `json:"answer,omitempty"`
+	Answers map[string]string `json:"answers,omitempty"`
+	Order   map[string]int64  `json:"order,omitempty"`
+}
+
+// Option represents a single answer a voter can choose.
+type Option struct {
+	Value       string `json:"value"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Answer represents the type of answer a poll expects: choice, text or number.
+// Min and Max bound number answers.
+type Answer struct {
+	Type string   `json:"type,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// Question represents one question of a multi-question poll.
+type Question struct {
+	ID      string   `json:"id"`
+	Prompt  string   `json:"prompt"`
+	Options []Option `json:"options,omitempty"`
+}
+
+// MenuItem represents an item voters can order in a meal poll.
+type MenuItem struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency,omitempty"`
 }
 
 // Message represents the structure of a message.
 type Message struct {
-	Question string `json:"question"`
-	Response string `json:"response"`
-	Result   string `json:"result"`
+	Question       string `json:"question"`
+	Response       string `json:"response"`
+	Result         string `json:"result"`
+	Delivery       string `json:"delivery,omitempty"`
+	PickupReminder string `json:"pickupReminder,omitempty"`
+}
+
+// ClaimRef references the PollClaim the poll was created for.
+type ClaimRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 // Poll represents the structure of a poll.
@@ -42,84 +102,319 @@ type Poll struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec              struct {
-		DeliveryTime int64   `json:"deliveryTime"`
-		DueOrderTime int64   `json:"dueOrderTime"`
-		DueTakeTime  int64   `json:"dueTakeTime"`
-		Schedule     string  `json:"schedule"`
-		Voters       []Voter `json:"voters"`
-		Title        string  `json:"title"`
-		Messages     Message `json:"messages"`
+		ClaimRef     *ClaimRef          `json:"claimRef,omitempty"`
+		DeliveryTime int64              `json:"deliveryTime"`
+		DueOrderTime intstr.IntOrString `json:"dueOrderTime"`
+		CloseAt      string             `json:"closeAt,omitempty"`
+		DueTakeTime  int64              `json:"dueTakeTime"`
+		Schedule     string             `json:"schedule"`
+		Voters       []Voter            `json:"voters"`
+		Title        string             `json:"title"`
+		Messages     Message            `json:"messages"`
+		Options      []Option           `json:"options,omitempty"`
+		Selection    string             `json:"selection,omitempty"`
+		Method       string             `json:"method,omitempty"`
+		Answer       Answer             `json:"answer,omitempty"`
+		Questions    []Question         `json:"questions,omitempty"`
+		Menu         []MenuItem         `json:"menu,omitempty"`
+		Calendar     Calendar           `json:"calendar,omitempty"`
+		Organizers   []string           `json:"organizers,omitempty"`
 	} `json:"spec"`
 	Status struct {
-		Done                 bool  `json:"done"`
-		LastNotificationTime int64 `json:"lastNotificationTime"`
+		Done                 bool            `json:"done"`
+		LastNotificationTime int64           `json:"lastNotificationTime"`
+		Phase                Phase           `json:"phase,omitempty"`
+		PhaseTimestamps      PhaseTimestamps `json:"phaseTimestamps,omitempty"`
+		Round                string          `json:"round,omitempty"`
+		CloseTime            int64           `json:"closeTime,omitempty"`
+		SkippedRounds        []SkippedRound  `json:"skippedRounds,omitempty"`
 	} `json:"status"`
 }
 
 // getK8sResource gets the Kubernetes resource.
 func getK8sResource(dynamicClient dynamic.Interface, ctx context.Context, pollSlackName string, resId schema.GroupVersionResource) (*Poll, error) {
-
-	res, err := dynamicClient.Resource(resId).Namespace("").
-		List(ctx, metav1.ListOptions{})
+	item, err := dynamicClient.Resource(resId).Get(ctx, pollSlackName, metav1.GetOptions{})
 	if err != nil {
 		return nil, err
 	}
-	for _, item := range res.Items {
-		res := &Poll{}
-		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, res); err != nil {
-			return nil, fmt.Errorf("error converting Unstructured to Poll struct: %v", err)
-		}
-		if res.GetObjectMeta().GetName() == pollSlackName {
-			return res, nil
+	res := &Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(item.Object, res); err != nil {
+		return nil, fmt.Errorf("error converting Unstructured to Poll struct: %v", err)
+	}
+	return res, nil
+}
+
+// pollOptions returns the options of the poll, falling back to Yes/No.
+func pollOptions(options []Option) []Option {
+	if len(options) == 0 {
+		return []Option{{Value: "Yes"}, {Value: "No"}}
+	}
+	return options
+}
+
+// optionLabel returns the text shown to users for an option.
+func optionLabel(option Option) string {
+	if option.Label != "" {
+		return option.Label
+	}
+	return option.Value
+}
+
+// actionOptions converts the poll options to Slack select options.
+func actionOptions(options []Option) []slack.AttachmentActionOption {
+	actionOptions := make([]slack.AttachmentActionOption, 0, len(options))
+	for _, option := range options {
+		actionOptions = append(actionOptions, slack.AttachmentActionOption{Text: optionLabel(option), Value: option.Value, Description: option.Description})
+	}
+	return actionOptions
+}
+
+// blockOptions converts the poll options to Slack block kit options.
+func blockOptions(options []Option) []*slack.OptionBlockObject {
+	blockOptions := make([]*slack.OptionBlockObject, 0, len(options))
+	for _, option := range options {
+		blockOptions = append(blockOptions, slack.NewOptionBlockObject(option.Value, slack.NewTextBlockObject(slack.PlainTextType, optionLabel(option), false, false)))
+	}
+	return blockOptions
+}
+
+// pollMessage builds the message content for the poll. Single selection polls
+// use a legacy attachment select, multiple selection polls need a block kit
+// multi-select because attachments don't support it.
+func pollMessage(poll *Poll) []slack.MsgOption {
+	if len(poll.Spec.Menu) > 0 {
+		return menuMessage(poll.Spec.Menu)
+	}
+	if len(poll.Spec.Questions) > 0 {
+		return questionsMessage(poll.Spec.Questions)
+	}
+	if poll.Spec.Answer.Type == "text" || poll.Spec.Answer.Type == "number" {
+		return answerMessage()
+	}
+	options := pollOptions(poll.Spec.Options)
+	if poll.Spec.Method == "rankedChoice" {
+		return rankingMessage(options)
+	}
+	if poll.Spec.Selection == "multiple" {
+		text := slack.NewTextBlockObject(slack.MarkdownType, "*"+pollTitle+"*\n"+slackNotifyMessage, false, false)
+		placeholder := slack.NewTextBlockObject(slack.PlainTextType, "Select one or more", false, false)
+		multiSelect := slack.NewOptionsSelectBlockElement("multi_static_select", placeholder, "actionMultiSelect", blockOptions(options)...)
+		return []slack.MsgOption{
+			slack.MsgOptionText(pollTitle, false),
+			slack.MsgOptionBlocks(
+				slack.NewSectionBlock(text, nil, nil),
+				slack.NewActionBlock(callbackID, multiSelect),
+			),
 		}
 	}
-	return nil, fmt.Errorf("poll resource with name %s not found", pollSlackName)
+
+	attachment := slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: callbackID,
+		Title:      pollTitle,
+		TitleLink:  pollTitle,
+		Text:       slackNotifyMessage,
+		Fields:     []slack.AttachmentField{},
+		Actions:    []slack.AttachmentAction{{Name: "actionSelect", Type: "select", Options: actionOptions(options)}, {Name: "actionCancel", Text: "Cancel", Type: "button", Style: "danger"}},
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText("", true),
+		slack.MsgOptionAttachments(attachment),
+	}
 }
 
-func main() {
-	config, err := ctrl.GetConfig()
-	if err != nil {
-		fmt.Println("error getting config", err)
+// maxQuantity is the largest quantity of a menu item a voter can order.
+const maxQuantity = 10
+
+// menuMessage builds a block kit message with a quantity select per menu item.
+// Like questions, the block ID carries the callback ID and the item ID.
+func menuMessage(menu []MenuItem) []slack.MsgOption {
+	quantities := make([]*slack.OptionBlockObject, 0, maxQuantity+1)
+	for i := 0; i <= maxQuantity; i++ {
+		quantities = append(quantities, slack.NewOptionBlockObject(strconv.Itoa(i), slack.NewTextBlockObject(slack.PlainTextType, strconv.Itoa(i), false, false)))
 	}
-	client, err := dynamic.NewForConfig(config)
-	if err != nil {
-		fmt.Println("error getting client", err)
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "*"+pollTitle+"*\n"+slackNotifyMessage, false, false), nil, nil),
+	}
+	for _, item := range menu {
+		price := strings.TrimSpace(strconv.FormatFloat(item.Price, 'f', 2, 64) + " " + item.Currency)
+		placeholder := slack.NewTextBlockObject(slack.PlainTextType, "Quantity", false, false)
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, "*"+item.Name+"* — "+price, false, false),
+			nil,
+			slack.NewAccessory(slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, placeholder, "order", quantities...)),
+			slack.SectionBlockOptionBlockID(callbackID+"/"+item.ID),
+		))
 	}
-	resourceId := schema.GroupVersionResource{
-		Group:    "kndp.io",
-		Version:  "v1alpha1",
-		Resource: "polls",
+	return []slack.MsgOption{
+		slack.MsgOptionText(pollTitle, false),
+		slack.MsgOptionBlocks(blocks...),
 	}
+}
 
-	pollResource, _ := getK8sResource(client, context.Background(), pollName, resourceId)
-	pollResource.GetObjectMeta().SetManagedFields(nil)
-	pollBytes, _ := json.Marshal(pollResource)
-	_, err = client.Resource(resourceId).Namespace("").Patch(context.Background(), pollResource.GetObjectMeta().GetName(), types.MergePatchType, pollBytes, metav1.PatchOptions{FieldManager: "slack-collector"})
-	if err != nil {
-		fmt.Println("Error patching poll resource", err)
+// questionsMessage builds a block kit message with a select per question. The
+// block ID of every question carries the callback ID and the question ID,
+// separated by a slash, so the collector can tell the answers apart.
+func questionsMessage(questions []Question) []slack.MsgOption {
+	blocks := []slack.Block{
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, "*"+pollTitle+"*\n"+slackNotifyMessage, false, false), nil, nil),
+	}
+	for _, question := range questions {
+		placeholder := slack.NewTextBlockObject(slack.PlainTextType, "Select an answer", false, false)
+		selectElement := slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, placeholder, "question", blockOptions(pollOptions(question.Options))...)
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, question.Prompt, false, false),
+			nil,
+			slack.NewAccessory(selectElement),
+			slack.SectionBlockOptionBlockID(callbackID+"/"+question.ID),
+		))
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText(pollTitle, false),
+		slack.MsgOptionBlocks(blocks...),
 	}
+}
 
-	statusBytes, _ := json.Marshal(map[string]interface{}{
-		"status": map[string]interface{}{
-			"done":                 false,
-			"lastNotificationTime": time.Now().Unix(),
-		},
-	})
+// answerMessage builds a message with an Answer button. The collector opens a
+// modal for free-text and numeric answers when the button is clicked.
+func answerMessage() []slack.MsgOption {
+	attachment := slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: callbackID,
+		Title:      pollTitle,
+		TitleLink:  pollTitle,
+		Text:       slackNotifyMessage,
+		Fields:     []slack.AttachmentField{},
+		Actions:    []slack.AttachmentAction{{Name: "actionAnswer", Text: "Answer", Type: "button", Value: callbackID, Style: "primary"}},
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText("", true),
+		slack.MsgOptionAttachments(attachment),
+	}
+}
 
+// rankingMessage builds a block kit message with one select per rank. The
+// action ID of every select carries its rank, starting at 1.
+func rankingMessage(options []Option) []slack.MsgOption {
+	text := slack.NewTextBlockObject(slack.MarkdownType, "*"+pollTitle+"*\n"+slackNotifyMessage+"\nRank the options, starting with your favourite.", false, false)
+	selects := make([]slack.BlockElement, 0, len(options))
+	for i := range options {
+		placeholder := slack.NewTextBlockObject(slack.PlainTextType, fmt.Sprintf("Choice #%d", i+1), false, false)
+		selects = append(selects, slack.NewOptionsSelectBlockElement(slack.OptTypeStatic, placeholder, fmt.Sprintf("rank_%d", i+1), blockOptions(options)...))
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText(pollTitle, false),
+		slack.MsgOptionBlocks(
+			slack.NewSectionBlock(text, nil, nil),
+			slack.NewActionBlock(callbackID, selects...),
+		),
+	}
+}
+
+// patchStatus merges the given fields into the poll status.
+func patchStatus(poll *Poll, status map[string]interface{}, client dynamic.Interface, ctx context.Context) error {
+	statusBytes, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
 	// Use the "/status" subresource to update just the status
 	_, err = client.Resource(resourceId).Namespace("").Patch(
-		context.Background(),
-		pollResource.GetObjectMeta().GetName(),
+		ctx,
+		poll.GetObjectMeta().GetName(),
 		types.MergePatchType,
 		statusBytes,
 		metav1.PatchOptions{FieldManager: "slack-collector"},
 		"/status",
 	)
+	return err
+}
+
+// isOrganizer checks if the user organizes the poll.
+func isOrganizer(poll *Poll, user string) bool {
+	for _, organizer := range poll.Spec.Organizers {
+		if organizer == user {
+			return true
+		}
+	}
+	return false
+}
+
+// organizerControls builds the message with the buttons organizers control
+// the round with. The collector checks that the user clicking them is an
+// organizer.
+func organizerControls() []slack.MsgOption {
+	attachment := slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: callbackID,
+		Title:      pollTitle,
+		Text:       "You organize this poll.",
+		Actions: []slack.AttachmentAction{
+			{Name: "organizerClose", Text: "Close now", Type: "button"},
+			{Name: "organizerExtend", Text: "Extend by 15 minutes", Type: "button"},
+			{Name: "organizerCancel", Text: "Cancel round", Type: "button", Style: "danger", Confirm: &slack.ConfirmationField{
+				Title:       "Cancel this round?",
+				Text:        "The round closes without posting results.",
+				OkText:      "Cancel round",
+				DismissText: "Keep it",
+			}},
+		},
+	}
+	return []slack.MsgOption{
+		slack.MsgOptionText("", true),
+		slack.MsgOptionAttachments(attachment),
+	}
+}
+
+// notify opens a new round of the poll and sends the poll message to the
+// members of the channel, unless the round is skipped. Nothing is sent if the
+// round couldn't be opened, so votes aren't cast on a round the poll doesn't
+// know about.
+func notify(client dynamic.Interface, ctx context.Context, now time.Time) error {
+	pollResource, err := getK8sResource(client, ctx, pollName, resourceId)
 	if err != nil {
-		fmt.Println("Error patching poll status", err)
+		return fmt.Errorf("cannot get poll: %w", err)
+	}
+	if phase := pollResource.phase(); !canTransition(phase, PhaseOpen) {
+		fmt.Println("skipping round, poll is still in phase", phase)
+		return nil
 	}
 
-	api := slack.New(token)
+	today := now.In(pollLocation())
+	excluded, err := resolveCalendar(pollResource.Spec.Calendar, client, ctx)
+	if err != nil {
+		fmt.Println("error resolving calendar", err)
+	}
+	upcoming := upcomingDates(excluded, today)
+	if source, ok := excluded[today.Format(dateLayout)]; ok {
+		fmt.Println("skipping round, excluded by", source)
+		skipped := SkippedRound{Date: today.Format(dateLayout), Time: today.Unix(), Source: source}
+		if err := skipRound(pollResource, skipped, upcoming, client, ctx); err != nil {
+			return fmt.Errorf("cannot skip round: %w", err)
+		}
+		return nil
+	}
+
+	round := roundID(today.Unix())
+	callbackID = roundCallbackID(pollRef(pollResource), round)
+	err = patchStatus(pollResource, map[string]interface{}{
+		"done":                 false,
+		"lastNotificationTime": today.Unix(),
+		"round":                round,
+		"voters":               []Voter{},
+		// The function computes the close time of the new round.
+		"closeTime":     nil,
+		"extension":     nil,
+		"excludedDates": upcoming,
+		"phase":         PhaseOpen,
+		"phaseTimestamps": map[string]interface{}{
+			"open": today.Unix(),
+		},
+	}, client, ctx)
+	if err != nil {
+		return fmt.Errorf("cannot open round: %w", err)
+	}
+
+	message := pollMessage(pollResource)
 
 	members, _, err := api.GetUsersInConversation(&slack.GetUsersInConversationParameters{
 		ChannelID: channelID,
@@ -135,27 +430,37 @@ func main() {
 			continue
 		}
 
-		attachment := slack.Attachment{
-			Color:      "#f9a41b",
-			CallbackID: pollName,
-			Title:      pollTitle,
-			TitleLink:  pollTitle,
-			Text:       slackNotifyMessage,
-			Fields:     []slack.AttachmentField{},
-			Actions:    []slack.AttachmentAction{{Name: "actionSelect", Type: "select", Options: []slack.AttachmentActionOption{{Text: "Yes", Value: "Yes"}, {Text: "No", Value: "No"}}}, {Name: "actionCancel", Text: "Cancel", Type: "button", Style: "danger"}},
-		}
-
 		channelID, _, err := api.PostMessage(
 			userInfo.ID,
-			slack.MsgOptionText("", true),
-			slack.MsgOptionAttachments(attachment),
-			slack.MsgOptionAsUser(true),
+			append(message, slack.MsgOptionAsUser(true))...,
 		)
 		if err != nil {
 			fmt.Println("error sending message to user in channel: ", userInfo.Name, channelID, err)
 		} else {
 			fmt.Println("message sent to user in channel: ", userInfo.Name, channelID)
 		}
+		if isOrganizer(pollResource, userInfo.Name) {
+			if _, _, err := api.PostMessage(userInfo.ID, append(organizerControls(), slack.MsgOptionAsUser(true))...); err != nil {
+				fmt.Println("error sending organizer controls to user: ", userInfo.Name, err)
+			}
+		}
+
+	}
+	return nil
+}
+
+func main() {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		fmt.Println("error getting config", err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		fmt.Println("error getting client", err)
+	}
 
+	if err := notify(client, context.Background(), time.Now()); err != nil {
+		fmt.Println("error notifying", err)
+		os.Exit(1)
 	}
 }
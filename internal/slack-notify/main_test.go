@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/nlopes/slack"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestNotify(t *testing.T) {
+	now := time.Date(2024, 12, 23, 11, 0, 0, 0, time.UTC)
+	pollName = "lunch"
+	pollTitle = "lunch"
+
+	errBoom := errors.New("boom")
+
+	type want struct {
+		err        error
+		phase      string
+		round      string
+		skipped    int
+		messages   map[string]int
+		callbackID string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		status   map[string]interface{}
+		spec     map[string]interface{}
+		patchErr error
+		want     want
+	}{
+		"Open": {
+			reason: "A new round should be opened and the poll sent to every member, organizers also get the controls",
+			spec:   map[string]interface{}{"organizers": []interface{}{"alice"}},
+			want:   want{phase: "Open", round: "1734951600", messages: map[string]int{"U1": 2, "U2": 1}, callbackID: "lunch@1734951600"},
+		},
+		"StillOpen": {
+			reason: "No round should be opened while the previous one is still open",
+			status: map[string]interface{}{"phase": "Open", "round": "100"},
+			want:   want{phase: "Open", round: "100", messages: map[string]int{"U1": 0, "U2": 0}},
+		},
+		"OpenFailed": {
+			reason:   "Nothing should be sent if the round couldn't be opened",
+			spec:     map[string]interface{}{"organizers": []interface{}{"alice"}},
+			patchErr: errBoom,
+			want:     want{err: errBoom, messages: map[string]int{"U1": 0, "U2": 0}},
+		},
+		"Excluded": {
+			reason: "The round should be skipped on an excluded date",
+			spec:   map[string]interface{}{"calendar": map[string]interface{}{"excludedDates": []interface{}{"2024-12-23"}}},
+			want:   want{skipped: 1, messages: map[string]int{"U1": 0, "U2": 0}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := newFakeSlack(slack.User{ID: "U1", Name: "alice"}, slack.User{ID: "U2", Name: "bob"})
+			defer srv.Close()
+			srv.use(t)
+
+			spec := map[string]interface{}{"title": "lunch"}
+			for k, v := range tc.spec {
+				spec[k] = v
+			}
+			poll := &unstructured.Unstructured{Object: map[string]interface{}{
+				"apiVersion": "kndp.io/v1alpha1",
+				"kind":       "Poll",
+				"metadata":   map[string]interface{}{"name": "lunch"},
+				"spec":       spec,
+			}}
+			if tc.status != nil {
+				poll.Object["status"] = tc.status
+			}
+			client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{resourceId: "PollList"}, poll)
+			if tc.patchErr != nil {
+				client.PrependReactor("patch", "polls", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tc.patchErr
+				})
+			}
+			ctx := context.Background()
+
+			notifyErr := notify(client, ctx, now)
+
+			got, err := client.Resource(resourceId).Get(ctx, "lunch", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Get(...): %v", err)
+			}
+			phase, _, _ := unstructured.NestedString(got.Object, "status", "phase")
+			round, _, _ := unstructured.NestedString(got.Object, "status", "round")
+			skipped, _, _ := unstructured.NestedSlice(got.Object, "status", "skippedRounds")
+			messages := map[string]int{}
+			for _, user := range []string{"U1", "U2"} {
+				messages[user] = len(srv.messagesTo(user))
+			}
+			var callbackID string
+			if posted := srv.messagesTo("U2"); len(posted) > 0 && len(posted[0].Attachments) > 0 {
+				callbackID = posted[0].Attachments[0].CallbackID
+			}
+			if diff := cmp.Diff(tc.want, want{err: notifyErr, phase: phase, round: round, skipped: len(skipped), messages: messages, callbackID: callbackID}, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("%s\nnotify(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// TestOpenRoundPatch checks the status the notifier writes when it opens a
+// round against the one the function is tested with, see TestPollRound.
+func TestOpenRoundPatch(t *testing.T) {
+	now := time.Unix(roundFixtureTime, 0).UTC()
+	pollName = "lunch"
+	pollTitle = "lunch"
+
+	srv := newFakeSlack(slack.User{ID: "U1", Name: "alice"}, slack.User{ID: "U2", Name: "bob"})
+	defer srv.Close()
+	srv.use(t)
+	poll := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "kndp.io/v1alpha1",
+		"kind":       "Poll",
+		"metadata":   map[string]interface{}{"name": "lunch"},
+		"spec":       map[string]interface{}{"title": "lunch"},
+	}}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{resourceId: "PollList"}, poll)
+	patches := []map[string]interface{}{}
+	client.PrependReactor("patch", "polls", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := map[string]interface{}{}
+		if err := json.Unmarshal(action.(k8stesting.PatchAction).GetPatch(), &patch); err != nil {
+			t.Error(err)
+		}
+		patches = append(patches, patch)
+		return false, nil, nil
+	})
+
+	if err := notify(client, context.Background(), now); err != nil {
+		t.Fatalf("notify(...): %v", err)
+	}
+
+	want := map[string]interface{}{}
+	if err := json.Unmarshal(readRoundFixture(t, "open.json"), &want); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]map[string]interface{}{want}, patches); diff != "" {
+		t.Errorf("notify(...): -want, +got:\n%s", diff)
+	}
+}
+
+// roundFixtureTime is the time the round of the fixtures shared with the
+// function and the collector opened at.
+const roundFixtureTime = 1734951600
+
+// readRoundFixture reads a status patch of the round the function, the
+// notifier and the collector are tested with.
+func readRoundFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "testdata", "round", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os"
+
+	"github.com/nlopes/slack"
+)
+
+// Messenger is the part of the Slack Web API the notifier uses. It's
+// implemented by *slack.Client.
+type Messenger interface {
+	GetUsersInConversation(params *slack.GetUsersInConversationParameters) ([]string, string, error)
+	GetUserInfo(user string) (*slack.User, error)
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+}
+
+// newSlackClient returns a Slack client with the token. SLACK_API_URL points
+// it to another Slack Web API, like the fake one of the tests.
+func newSlackClient(token string) *slack.Client {
+	if url := os.Getenv("SLACK_API_URL"); url != "" {
+		return slack.New(token, slack.OptionAPIURL(url))
+	}
+	return slack.New(token)
+}
@@ -0,0 +1,18 @@
+package main
+
+import "strconv"
+
+// roundSeparator separates the poll name from the round in callback and
+// block IDs. Kubernetes names can't contain it.
+const roundSeparator = "@"
+
+// roundID identifies a poll round by the time it was opened.
+func roundID(openedAt int64) string {
+	return strconv.FormatInt(openedAt, 10)
+}
+
+// roundCallbackID stamps the poll name with the round so the collector can
+// reject votes cast on messages of an earlier round.
+func roundCallbackID(pollName, round string) string {
+	return pollName + roundSeparator + round
+}
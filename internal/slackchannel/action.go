@@ -0,0 +1,154 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Annotations organizers request an action on a running poll with. The
+// action is applied once; to repeat it, change the action ID or remove the
+// annotation first.
+const (
+	ActionAnnotation   = "poll.kndp.io/action"
+	ActionIDAnnotation = "poll.kndp.io/action-id"
+	ActionByAnnotation = "poll.kndp.io/action-by"
+)
+
+// Actions organizers can request. Extend takes an optional duration after a
+// colon, like "extend:30m".
+const (
+	ActionClose  = "close"
+	ActionExtend = "extend"
+	ActionReopen = "reopen"
+	ActionCancel = "cancel"
+)
+
+// defaultExtension is how long extend adds to the round and how long a
+// reopened round stays open at least.
+const defaultExtension = 15 * time.Minute
+
+// maxActions is the number of actions kept in status.actions.
+const maxActions = 20
+
+// ActionRecord records an action requested by an organizer and whether it
+// could be applied.
+type ActionRecord struct {
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+	By     string `json:"by,omitempty"`
+	Round  string `json:"round,omitempty"`
+	Time   int64  `json:"time"`
+	Error  string `json:"error,omitempty"`
+}
+
+// reopenVoters restores the votes of the round whose results were posted and
+// removes it from the history, it's recorded again when it closes. Votes of
+// anonymized rounds can't be restored.
+func (p *Poll) reopenVoters() {
+	last := len(p.Status.Rounds) - 1
+	if last < 0 || p.Status.Rounds[last].ID != p.Status.Round || p.Spec.History.Anonymize {
+		return
+	}
+	p.Status.Voters = p.Status.Rounds[last].Voters
+	p.Status.Rounds = p.Status.Rounds[:last]
+}
+
+// applyAction applies an organizer action to the poll.
+func (p *Poll) applyAction(action string, now int64) error {
+	name, arg, _ := strings.Cut(action, ":")
+	switch name {
+	case ActionClose:
+		return p.setPhase(PhaseClosing, now)
+	case ActionExtend:
+		if phase := p.phase(); phase != PhaseOpen {
+			return fmt.Errorf("cannot extend poll in phase %s", phase)
+		}
+		d := defaultExtension
+		if arg != "" {
+			var err error
+			if d, err = ParseDuration(arg); err != nil {
+				return err
+			}
+		}
+		p.Status.Extension += int64(d / time.Second)
+		return nil
+	case ActionReopen:
+		from := p.phase()
+		if err := p.setPhase(PhaseOpen, now); err != nil {
+			return err
+		}
+		if from == PhaseResultsPosted {
+			p.reopenVoters()
+			// The results of the round are posted again when it closes.
+			p.Status.Results = nil
+		}
+		if until := now + int64(defaultExtension/time.Second); p.Status.CloseTime != 0 && p.Status.CloseTime < until {
+			p.Status.Extension += until - p.Status.CloseTime
+		}
+		return nil
+	case ActionCancel:
+		return p.setPhase(PhaseCancelled, now)
+	default:
+		return fmt.Errorf("unknown action %q", name)
+	}
+}
+
+// ApplyAction applies the action requested through the annotations of the
+// poll composite resource and records it in status.actions. It returns the
+// record of the action, or nil if no new action was requested.
+func ApplyAction(xr *resource.Composite, currentTimestamp int) (*ActionRecord, error) {
+	annotations := xr.Resource.GetAnnotations()
+	action := annotations[ActionAnnotation]
+	if action == "" {
+		return nil, nil
+	}
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return nil, fmt.Errorf("cannot convert composite to Poll: %w", err)
+	}
+	id := annotations[ActionIDAnnotation]
+	if n := len(poll.Status.Actions); n > 0 && poll.Status.Actions[n-1].Action == action && poll.Status.Actions[n-1].ID == id {
+		return nil, nil
+	}
+
+	now := int64(currentTimestamp)
+	record := ActionRecord{Action: action, ID: id, By: annotations[ActionByAnnotation], Round: poll.Status.Round, Time: now}
+	// Rejected actions are recorded too, so they aren't retried on every
+	// reconcile.
+	if err := poll.applyAction(action, now); err != nil {
+		record.Error = err.Error()
+	}
+	actions := append(poll.Status.Actions, record)
+	if len(actions) > maxActions {
+		actions = actions[len(actions)-maxActions:]
+	}
+	poll.Status.Actions = actions
+
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&poll.Status)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert Poll status to Unstructured: %w", err)
+	}
+	if conditions, err := xr.Resource.GetValue("status.conditions"); err == nil {
+		status["conditions"] = conditions
+	}
+	return &record, xr.Resource.SetValue("status", status)
+}
+
+// reopenAttachment returns the attachment with the button organizers reopen
+// the round of the posted results with.
+func reopenAttachment(poll Poll) slack.Attachment {
+	return slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: pollRef(poll) + "@" + poll.Status.Round,
+		Text:       "Organizers can reopen the poll if it closed by mistake.",
+		Actions: []slack.AttachmentAction{
+			{Name: "organizerReopen", Text: "Reopen", Type: "button"},
+		},
+	}
+}
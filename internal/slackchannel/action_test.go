@@ -0,0 +1,133 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+)
+
+func TestApplyAction(t *testing.T) {
+	poll := func(phase Phase, closeTime, extension int64, voters []Voter, rounds []Round) Poll {
+		p := Poll{}
+		p.Status.Phase = phase
+		p.Status.Done = phase != PhaseOpen
+		p.Status.LastNotificationTime = 50
+		p.Status.Round = "50"
+		p.Status.CloseTime = closeTime
+		p.Status.Extension = extension
+		p.Status.Voters = voters
+		p.Status.Rounds = rounds
+		return p
+	}
+	votes := []Voter{{Name: "alice", Status: "Yes"}}
+
+	type want struct {
+		poll Poll
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		poll   Poll
+		action string
+		want   want
+	}{
+		"Close": {
+			reason: "Closing an open poll should move it to Closing",
+			poll:   poll(PhaseOpen, 1000, 0, nil, nil),
+			action: ActionClose,
+			want: want{poll: func() Poll {
+				p := poll(PhaseClosing, 1000, 0, nil, nil)
+				p.Status.PhaseTimestamps.Closing = 100
+				return p
+			}()},
+		},
+		"Extend": {
+			reason: "Extending an open poll should add 15 minutes to the round",
+			poll:   poll(PhaseOpen, 1000, 60, nil, nil),
+			action: ActionExtend,
+			want:   want{poll: poll(PhaseOpen, 1000, 960, nil, nil)},
+		},
+		"ExtendBy": {
+			reason: "Extending an open poll by a duration should add it to the round",
+			poll:   poll(PhaseOpen, 1000, 0, nil, nil),
+			action: "extend:1h",
+			want:   want{poll: poll(PhaseOpen, 1000, 3600, nil, nil)},
+		},
+		"ExtendClosed": {
+			reason: "A closed poll can't be extended",
+			poll:   poll(PhaseClosed, 1000, 0, nil, nil),
+			action: ActionExtend,
+			want:   want{poll: poll(PhaseClosed, 1000, 0, nil, nil), err: true},
+		},
+		"Reopen": {
+			reason: "Reopening a poll whose results were posted should restore the votes of the round, keep it open for 15 minutes and post its results again",
+			poll: func() Poll {
+				p := poll(PhaseResultsPosted, 80, 0, []Voter{}, []Round{{ID: "40"}, {ID: "50", Voters: votes}})
+				p.Status.Results = &ResultsStatus{Round: "50", Timestamp: "60.000001"}
+				return p
+			}(),
+			action: ActionReopen,
+			want: want{poll: func() Poll {
+				p := poll(PhaseOpen, 80, 920, votes, []Round{{ID: "40"}})
+				p.Status.PhaseTimestamps.Open = 100
+				return p
+			}()},
+		},
+		"Cancel": {
+			reason: "Cancelling an open poll should move it to Cancelled",
+			poll:   poll(PhaseOpen, 1000, 0, votes, nil),
+			action: ActionCancel,
+			want: want{poll: func() Poll {
+				p := poll(PhaseCancelled, 1000, 0, votes, nil)
+				p.Status.PhaseTimestamps.Cancelled = 100
+				return p
+			}()},
+		},
+		"Unknown": {
+			reason: "Unknown actions should be rejected",
+			poll:   poll(PhaseOpen, 1000, 0, nil, nil),
+			action: "pause",
+			want:   want{poll: poll(PhaseOpen, 1000, 0, nil, nil), err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := tc.poll
+			err := p.applyAction(tc.action, 100)
+			if diff := cmp.Diff(tc.want, want{poll: p, err: err != nil}, cmp.AllowUnexported(want{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("%s\napplyAction(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestApplyActionOnce(t *testing.T) {
+	xr := &resource.Composite{Resource: composite.New()}
+	xr.Resource.SetAnnotations(map[string]string{ActionAnnotation: ActionExtend, ActionIDAnnotation: "1", ActionByAnnotation: "alice"})
+	if err := xr.Resource.SetString("status.phase", string(PhaseOpen)); err != nil {
+		t.Fatal(err)
+	}
+	if err := xr.Resource.SetInteger("status.lastNotificationTime", 50); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []*ActionRecord{{Action: ActionExtend, ID: "1", By: "alice", Time: 100}, nil} {
+		got, err := ApplyAction(xr, 100)
+		if err != nil {
+			t.Fatalf("ApplyAction(...): %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("ApplyAction(...) #%d: -want, +got:\n%s", i+1, diff)
+		}
+	}
+	extension, _ := xr.Resource.GetInteger("status.extension")
+	if diff := cmp.Diff(int64(900), extension); diff != "" {
+		t.Errorf("ApplyAction(...): the action should be applied once: -want extension, +got extension:\n%s", diff)
+	}
+}
@@ -0,0 +1,99 @@
+package slackchannel
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// dateLayout is the layout of excluded dates.
+const dateLayout = "2006-01-02"
+
+// maxSkippedRuns bounds the runs nextRun skips looking for one that isn't
+// excluded.
+const maxSkippedRuns = 1000
+
+// ConfigMapRef references a key of a ConfigMap holding an ICS calendar.
+type ConfigMapRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key,omitempty"`
+}
+
+// CalendarRef references a cluster scoped PollCalendar.
+type CalendarRef struct {
+	Name string `json:"name"`
+}
+
+// Calendar lists the days on which the poll doesn't run. Dates are in the
+// time zone of the poll.
+type Calendar struct {
+	ExcludedDates []string      `json:"excludedDates,omitempty"`
+	ConfigMapRef  *ConfigMapRef `json:"configMapRef,omitempty"`
+	CalendarRef   *CalendarRef  `json:"calendarRef,omitempty"`
+}
+
+// SkippedRound records a scheduled round the notifier skipped.
+type SkippedRound struct {
+	Date   string `json:"date"`
+	Time   int64  `json:"time"`
+	Source string `json:"source,omitempty"`
+}
+
+// CalendarRequirement returns the name of the PollCalendar the poll
+// references, if any.
+func CalendarRequirement(xr *resource.Composite) string {
+	name, _ := xr.Resource.GetString("spec.calendar.calendarRef.name")
+	return name
+}
+
+// calendarDates returns the excluded dates of a PollCalendar. Dates of its
+// ICS calendar are resolved by the notifier and recorded in the poll status.
+func calendarDates(calendar *unstructured.Unstructured) []string {
+	dates, _, _ := unstructured.NestedStringSlice(calendar.Object, "spec", "excludedDates")
+	return dates
+}
+
+// nextRun returns the first run of the schedule after from that isn't on an
+// excluded date, or the zero time if there is none.
+func nextRun(s *cronSchedule, from time.Time, loc *time.Location, excluded map[string]bool) time.Time {
+	t := from
+	for i := 0; i < maxSkippedRuns; i++ {
+		t = s.next(t, loc)
+		if t.IsZero() || !excluded[t.Format(dateLayout)] {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// SetNextRun records the next effective run of the notifier in
+// status.nextRunTime. Dates excluded by the poll, by the referenced
+// PollCalendar and the dates the notifier resolved are skipped.
+func SetNextRun(xr *resource.Composite, loc *time.Location, now time.Time, calendar *unstructured.Unstructured) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return err
+	}
+	s, err := parseSchedule(poll.Spec.Schedule)
+	if err != nil {
+		return err
+	}
+	excluded := map[string]bool{}
+	dates := append(poll.Spec.Calendar.ExcludedDates, poll.Status.ExcludedDates...)
+	if calendar != nil {
+		dates = append(dates, calendarDates(calendar)...)
+	}
+	for _, date := range dates {
+		excluded[date] = true
+	}
+	next := nextRun(s, now, loc, excluded)
+	if next.IsZero() {
+		unstructured.RemoveNestedField(xr.Resource.Object, "status", "nextRunTime")
+		return nil
+	}
+	return xr.Resource.SetValue("status.nextRunTime", next.Unix())
+}
@@ -0,0 +1,86 @@
+package slackchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNextRun(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Monday, 2024-12-23 12:00 in Berlin.
+	now := time.Date(2024, 12, 23, 12, 0, 0, 0, berlin)
+
+	type args struct {
+		schedule string
+		excluded map[string]bool
+	}
+	type want struct {
+		next time.Time
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Weekdays": {
+			reason: "The next run should be on the next scheduled day in the poll's time zone",
+			args:   args{schedule: "0 11 * * 1-5"},
+			want:   want{next: time.Date(2024, 12, 24, 11, 0, 0, 0, berlin)},
+		},
+		"Excluded": {
+			reason: "Runs on excluded dates should be skipped",
+			args: args{
+				schedule: "0 11 * * mon-fri",
+				excluded: map[string]bool{"2024-12-24": true, "2024-12-25": true, "2024-12-26": true},
+			},
+			want: want{next: time.Date(2024, 12, 27, 11, 0, 0, 0, berlin)},
+		},
+		"Steps": {
+			reason: "Steps and lists should be supported",
+			args:   args{schedule: "*/20 9,13 * * *"},
+			want:   want{next: time.Date(2024, 12, 23, 13, 0, 0, 0, berlin)},
+		},
+		"DayOfMonthOrWeek": {
+			reason: "A day should match either the day of month or the day of week when both are restricted",
+			args:   args{schedule: "0 9 1 * 3"},
+			want:   want{next: time.Date(2024, 12, 25, 9, 0, 0, 0, berlin)},
+		},
+		"Descriptor": {
+			reason: "Schedule descriptors should be supported",
+			args:   args{schedule: "@monthly"},
+			want:   want{next: time.Date(2025, 1, 1, 0, 0, 0, 0, berlin)},
+		},
+		"Sunday": {
+			reason: "Sunday should also be 7",
+			args:   args{schedule: "30 10 * * 7"},
+			want:   want{next: time.Date(2024, 12, 29, 10, 30, 0, 0, berlin)},
+		},
+		"Invalid": {
+			reason: "Invalid schedules should be rejected",
+			args:   args{schedule: "0 25 * * *"},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := want{}
+			s, err := parseSchedule(tc.args.schedule)
+			if err != nil {
+				got.err = true
+			} else {
+				got.next = nextRun(s, now, berlin, tc.args.excluded)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nnextRun(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package slackchannel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// channelIDPattern matches the IDs of public and private channels and of
+// direct messages. Channel names are lowercase.
+var channelIDPattern = regexp.MustCompile(`^[CGD][A-Z0-9]{8,}$`)
+
+// ResolveChannel returns the ID of the channel, which can be given by ID or
+// by name with or without the leading #.
+func ResolveChannel(api Messenger, channel string) (string, error) {
+	if channelIDPattern.MatchString(channel) {
+		return channel, nil
+	}
+	name := strings.TrimPrefix(channel, "#")
+	params := &slack.GetConversationsParameters{
+		ExcludeArchived: true,
+		Limit:           1000,
+		Types:           []string{"public_channel", "private_channel"},
+	}
+	for {
+		channels, cursor, err := api.GetConversations(params)
+		if err != nil {
+			return "", fmt.Errorf("cannot list channels: %w", err)
+		}
+		for _, c := range channels {
+			if c.Name == name {
+				return c.ID, nil
+			}
+		}
+		if cursor == "" {
+			return "", fmt.Errorf("cannot find channel %q", channel)
+		}
+		params.Cursor = cursor
+	}
+}
@@ -0,0 +1,73 @@
+package slackchannel
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Labels of the Secrets polls may read their Slack credentials from. Their
+// values are the name and the namespace of the Secret. Crossplane can't fetch
+// namespaced resources for the function by name and namespace, only by label,
+// so the referenced Secret must carry both and only that Secret is fetched.
+const (
+	CredentialsLabel          = "poll.kndp.io/slack-credentials"
+	CredentialsNamespaceLabel = "poll.kndp.io/slack-credentials-namespace"
+)
+
+// DefaultTokenKey is the key of the Slack token in the credentials Secret.
+const DefaultTokenKey = "SLACK_API_TOKEN"
+
+// SecretKeyRef references a key of a Secret in the namespace the notifier and
+// the collector run in.
+type SecretKeyRef struct {
+	Name string `json:"name"`
+	Key  string `json:"key,omitempty"`
+}
+
+// CredentialsRef returns the reference to the Secret holding the Slack token
+// of the poll, or nil if the poll uses the token of the function.
+func CredentialsRef(xr *resource.Composite) *SecretKeyRef {
+	name, _ := xr.Resource.GetString("spec.slackCredentialsSecretRef.name")
+	if name == "" {
+		return nil
+	}
+	key, _ := xr.Resource.GetString("spec.slackCredentialsSecretRef.key")
+	if key == "" {
+		key = DefaultTokenKey
+	}
+	return &SecretKeyRef{Name: name, Key: key}
+}
+
+// CredentialsSelector returns the labels selecting the Secret the reference
+// points to in the namespace.
+func CredentialsSelector(ref SecretKeyRef, namespace string) map[string]string {
+	return map[string]string{
+		CredentialsLabel:          ref.Name,
+		CredentialsNamespaceLabel: namespace,
+	}
+}
+
+// SlackToken returns the token the reference points to among the Secrets
+// Crossplane fetched. Secrets whose labels don't match their name and
+// namespace are rejected rather than skipped.
+func SlackToken(ref SecretKeyRef, namespace string, secrets []*unstructured.Unstructured) (string, error) {
+	for _, secret := range secrets {
+		if secret.GetName() != ref.Name || secret.GetNamespace() != namespace {
+			return "", fmt.Errorf("secret %s/%s is labelled as the Slack credentials %s/%s", secret.GetNamespace(), secret.GetName(), namespace, ref.Name)
+		}
+		data, _, _ := unstructured.NestedString(secret.Object, "data", ref.Key)
+		if data == "" {
+			return "", fmt.Errorf("secret %s/%s has no key %q", namespace, ref.Name, ref.Key)
+		}
+		token, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return "", fmt.Errorf("cannot decode key %q of secret %s/%s: %w", ref.Key, namespace, ref.Name, err)
+		}
+		return string(token), nil
+	}
+	return "", fmt.Errorf("cannot find secret %s/%s labelled %s=%s and %s=%s", namespace, ref.Name, CredentialsLabel, ref.Name, CredentialsNamespaceLabel, namespace)
+}
@@ -0,0 +1,132 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/crossplane/function-template-go/internal/slacktest"
+)
+
+func TestSlackToken(t *testing.T) {
+	// secret returns a Secret labelled as the Slack credentials default/slack,
+	// like the ones Crossplane fetches for the poll.
+	secret := func(namespace, name string, data map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"namespace": namespace,
+				"name":      name,
+				"labels":    map[string]interface{}{CredentialsLabel: "slack", CredentialsNamespaceLabel: "default"},
+			},
+			"data": data,
+		}}
+	}
+	slack := secret("default", "slack", map[string]interface{}{"SLACK_API_TOKEN": "eG94Yi1kZWZhdWx0", "bot": "eG94Yi1ib3Q="})
+
+	type want struct {
+		token string
+		err   bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		ref     SecretKeyRef
+		secrets []*unstructured.Unstructured
+		want    want
+	}{
+		"DefaultKey": {
+			reason:  "The token should be read from the Secret in the namespace of the notifier and the collector",
+			ref:     SecretKeyRef{Name: "slack", Key: DefaultTokenKey},
+			secrets: []*unstructured.Unstructured{slack},
+			want:    want{token: "xoxb-default"},
+		},
+		"Key": {
+			reason:  "The token should be read from the referenced key",
+			ref:     SecretKeyRef{Name: "slack", Key: "bot"},
+			secrets: []*unstructured.Unstructured{slack},
+			want:    want{token: "xoxb-bot"},
+		},
+		"MissingKey": {
+			reason:  "A Secret without the key should be rejected",
+			ref:     SecretKeyRef{Name: "slack", Key: "token"},
+			secrets: []*unstructured.Unstructured{slack},
+			want:    want{err: true},
+		},
+		"OtherNamespace": {
+			reason:  "A Secret outside the namespace of the notifier and the collector should be rejected, even next to the referenced one",
+			ref:     SecretKeyRef{Name: "slack", Key: DefaultTokenKey},
+			secrets: []*unstructured.Unstructured{secret("team-a", "slack", map[string]interface{}{"SLACK_API_TOKEN": "eG94Yi10ZWFtLWE="}), slack},
+			want:    want{err: true},
+		},
+		"OtherName": {
+			reason:  "A Secret whose labels don't name it should be rejected",
+			ref:     SecretKeyRef{Name: "slack", Key: DefaultTokenKey},
+			secrets: []*unstructured.Unstructured{secret("default", "slack-team", map[string]interface{}{"SLACK_API_TOKEN": "eG94Yi10ZWFt"})},
+			want:    want{err: true},
+		},
+		"MissingSecret": {
+			reason: "A missing Secret should be rejected",
+			ref:    SecretKeyRef{Name: "slack", Key: DefaultTokenKey},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			token, err := SlackToken(tc.ref, "default", tc.secrets)
+			if diff := cmp.Diff(tc.want, want{token: token, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nSlackToken(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolveChannel(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	srv.SetChannels(slacktest.Channel("C0123456789", "general"), slacktest.Channel("C0987654321", "lunch"))
+
+	type want struct {
+		id  string
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		channel string
+		want    want
+	}{
+		"ID": {
+			reason:  "Channel IDs should be used as they are",
+			channel: "C0AAAAAAAAA",
+			want:    want{id: "C0AAAAAAAAA"},
+		},
+		"Name": {
+			reason:  "Channel names should be resolved to their ID",
+			channel: "lunch",
+			want:    want{id: "C0987654321"},
+		},
+		"HashName": {
+			reason:  "Channel names may start with a #",
+			channel: "#general",
+			want:    want{id: "C0123456789"},
+		},
+		"Unknown": {
+			reason:  "Unknown channels should be rejected",
+			channel: "dinner",
+			want:    want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			id, err := ResolveChannel(srv.Client(), tc.channel)
+			if diff := cmp.Diff(tc.want, want{id: id, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nResolveChannel(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors are the schedule shorthands CronJobs accept.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronBounds are the values a cron field can take.
+type cronBounds struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteBounds = cronBounds{min: 0, max: 59}
+	hourBounds   = cronBounds{min: 0, max: 23}
+	domBounds    = cronBounds{min: 1, max: 31}
+	monthBounds  = cronBounds{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Sunday is both 0 and 7.
+	dowBounds = cronBounds{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cronSchedule is a parsed five-field cron schedule. Every field is a bit set
+// of the values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// Like cron, a day matches either the day of month or the day of week
+	// when both are restricted.
+	domStar, dowStar bool
+}
+
+// parseCronValue parses a single number or name of a field.
+func parseCronValue(s string, bounds cronBounds) (int, error) {
+	if v, ok := bounds.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < bounds.min || v > bounds.max {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps.
+// It also reports whether the field is an unrestricted wildcard.
+func parseCronField(field string, bounds cronBounds) (uint64, bool, error) {
+	var bits uint64
+	star := false
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, false, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		lo, hi := bounds.min, bounds.max
+		if rangePart == "*" || rangePart == "?" {
+			star = star || step == 1
+		} else {
+			loPart, hiPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = parseCronValue(loPart, bounds); err != nil {
+				return 0, false, err
+			}
+			switch {
+			case isRange:
+				if hi, err = parseCronValue(hiPart, bounds); err != nil {
+					return 0, false, err
+				}
+			case !hasStep:
+				hi = lo
+			}
+			if lo > hi {
+				return 0, false, fmt.Errorf("invalid range %q", rangePart)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, star, nil
+}
+
+// parseSchedule parses a CronJob schedule.
+func parseSchedule(schedule string) (*cronSchedule, error) {
+	if expanded, ok := cronDescriptors[strings.TrimSpace(schedule)]; ok {
+		schedule = expanded
+	}
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected 5 fields", schedule)
+	}
+	s := &cronSchedule{}
+	var err error
+	if s.minute, _, err = parseCronField(fields[0], minuteBounds); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: minute: %w", schedule, err)
+	}
+	if s.hour, _, err = parseCronField(fields[1], hourBounds); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: hour: %w", schedule, err)
+	}
+	if s.dom, s.domStar, err = parseCronField(fields[2], domBounds); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of month: %w", schedule, err)
+	}
+	if s.month, _, err = parseCronField(fields[3], monthBounds); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: month: %w", schedule, err)
+	}
+	if s.dow, s.dowStar, err = parseCronField(fields[4], dowBounds); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: day of week: %w", schedule, err)
+	}
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+// dayMatches checks if the schedule runs on the day of t.
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first run of the schedule after t in loc, or the zero time
+// if it doesn't run within five years.
+func (s *cronSchedule) next(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, loc)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,132 @@
+package slackchannel
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// isoDuration matches the ISO-8601 durations without years and months, whose
+// length depends on the calendar.
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseDuration parses a Go duration like "45m" or an ISO-8601 duration like
+// "PT45M". A plain number is read as seconds. Durations must be positive.
+func ParseDuration(s string) (time.Duration, error) {
+	d, err := parseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q: must be positive", s)
+	}
+	return d, nil
+}
+
+// parseDuration parses a duration of any sign.
+func parseDuration(s string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[5] != "" {
+		seconds, err := strconv.ParseFloat(m[5], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		d += time.Duration(seconds * float64(time.Second))
+	}
+	return d, nil
+}
+
+// dueOrderDuration returns how long the poll accepts votes. Integers are
+// seconds, as before duration strings were supported.
+func dueOrderDuration(dueOrderTime intstr.IntOrString) (time.Duration, error) {
+	if dueOrderTime.Type == intstr.Int {
+		return time.Duration(dueOrderTime.IntValue()) * time.Second, nil
+	}
+	d, err := ParseDuration(dueOrderTime.StrVal)
+	if err != nil {
+		return 0, fmt.Errorf("invalid dueOrderTime: %w", err)
+	}
+	return d, nil
+}
+
+// nextClock returns the first time after from at which the wall clock in loc
+// shows the given "15:04" time.
+func nextClock(clock string, from time.Time, loc *time.Location) (time.Time, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid closeAt %q: expected HH:MM", clock)
+	}
+	from = from.In(loc)
+	next := time.Date(from.Year(), from.Month(), from.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	if !next.After(from) {
+		next = time.Date(from.Year(), from.Month(), from.Day()+1, t.Hour(), t.Minute(), 0, 0, loc)
+	}
+	return next, nil
+}
+
+// closeTime returns when the round that opened at openedAt stops accepting
+// votes: spec.dueOrderTime after it opened or at spec.closeAt, whichever
+// comes first.
+func closeTime(poll Poll, openedAt int64, loc *time.Location) (int64, error) {
+	due, err := dueOrderDuration(poll.Spec.DueOrderTime)
+	if err != nil {
+		return 0, err
+	}
+	opened := time.Unix(openedAt, 0)
+	if poll.Spec.CloseAt == "" {
+		return opened.Add(due).Unix(), nil
+	}
+	closeAt, err := nextClock(poll.Spec.CloseAt, opened, loc)
+	if err != nil {
+		return 0, err
+	}
+	if due > 0 && opened.Add(due).Before(closeAt) {
+		return opened.Add(due).Unix(), nil
+	}
+	return closeAt.Unix(), nil
+}
+
+// SetCloseTime validates the close condition of the poll and records the
+// close time of the open round in status.closeTime.
+func SetCloseTime(xr *resource.Composite, loc *time.Location) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return err
+	}
+	openedAt := poll.Status.LastNotificationTime
+	t, err := closeTime(poll, openedAt, loc)
+	if err != nil {
+		return err
+	}
+	if GetPhase(xr) != PhaseOpen || openedAt == 0 {
+		return nil
+	}
+	// Organizers may have extended the round.
+	return xr.Resource.SetValue("status.closeTime", t+poll.Status.Extension)
+}
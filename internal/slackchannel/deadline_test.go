@@ -0,0 +1,149 @@
+package slackchannel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestParseDuration(t *testing.T) {
+	type want struct {
+		d   time.Duration
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		s      string
+		want   want
+	}{
+		"Go": {
+			reason: "Go durations should be parsed",
+			s:      "1h30m",
+			want:   want{d: 90 * time.Minute},
+		},
+		"ISO8601": {
+			reason: "ISO-8601 durations should be parsed",
+			s:      "P1DT2H30M",
+			want:   want{d: 26*time.Hour + 30*time.Minute},
+		},
+		"ISO8601Seconds": {
+			reason: "ISO-8601 durations may have fractional seconds",
+			s:      "PT1.5S",
+			want:   want{d: 1500 * time.Millisecond},
+		},
+		"Seconds": {
+			reason: "Plain numbers should be read as seconds",
+			s:      "15",
+			want:   want{d: 15 * time.Second},
+		},
+		"Negative": {
+			reason: "Negative durations should be rejected",
+			s:      "-1h",
+			want:   want{err: true},
+		},
+		"NegativeSeconds": {
+			reason: "Negative numbers of seconds should be rejected",
+			s:      "-15",
+			want:   want{err: true},
+		},
+		"Zero": {
+			reason: "Zero durations should be rejected",
+			s:      "0s",
+			want:   want{err: true},
+		},
+		"ZeroISO8601": {
+			reason: "ISO-8601 durations of zero should be rejected",
+			s:      "PT0M",
+			want:   want{err: true},
+		},
+		"EmptyISO8601": {
+			reason: "ISO-8601 durations without any component should be rejected",
+			s:      "PT",
+			want:   want{err: true},
+		},
+		"Months": {
+			reason: "ISO-8601 durations with months should be rejected, their length depends on the calendar",
+			s:      "P1M",
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			d, err := ParseDuration(tc.s)
+			if diff := cmp.Diff(tc.want, want{d: d, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nParseDuration(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestCloseTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-03-30 10:00 in Berlin, the day before daylight saving time starts.
+	opened := time.Date(2024, 3, 30, 10, 0, 0, 0, berlin)
+
+	poll := func(dueOrderTime intstr.IntOrString, closeAt string) Poll {
+		p := Poll{}
+		p.Spec.DueOrderTime = dueOrderTime
+		p.Spec.CloseAt = closeAt
+		return p
+	}
+
+	type want struct {
+		t   time.Time
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		poll   Poll
+		want   want
+	}{
+		"Seconds": {
+			reason: "Integer dueOrderTime should still be read as seconds",
+			poll:   poll(intstr.FromInt32(900), ""),
+			want:   want{t: opened.Add(15 * time.Minute)},
+		},
+		"Duration": {
+			reason: "String dueOrderTime should be read as a duration",
+			poll:   poll(intstr.FromString("45m"), ""),
+			want:   want{t: opened.Add(45 * time.Minute)},
+		},
+		"CloseAt": {
+			reason: "closeAt should close the round at the wall-clock time in the poll's time zone",
+			poll:   poll(intstr.IntOrString{}, "11:30"),
+			want:   want{t: time.Date(2024, 3, 30, 11, 30, 0, 0, berlin)},
+		},
+		"CloseAtNextDay": {
+			reason: "closeAt earlier than the opening should close the round the next day, across the DST change",
+			poll:   poll(intstr.IntOrString{}, "09:00"),
+			want:   want{t: time.Date(2024, 3, 31, 9, 0, 0, 0, berlin)},
+		},
+		"Earlier": {
+			reason: "The earlier of dueOrderTime and closeAt should apply",
+			poll:   poll(intstr.FromString("PT30M"), "11:30"),
+			want:   want{t: opened.Add(30 * time.Minute)},
+		},
+		"InvalidDuration": {
+			reason: "Invalid durations should be rejected",
+			poll:   poll(intstr.FromString("soon"), ""),
+			want:   want{t: time.Unix(0, 0), err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := closeTime(tc.poll, opened.Unix(), berlin)
+			if diff := cmp.Diff(tc.want, want{t: time.Unix(got, 0), err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\ncloseTime(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+const (
+	defaultDeliveryText       = "Food has arrived! Please pick up your order."
+	defaultPickupReminderText = "Your order is still waiting for you, please pick it up."
+)
+
+// Delivery tracks the delivery and pickup phases after an order is placed.
+// Recipients are the voters who take part in the order.
+type Delivery struct {
+	OrderedAt   int64    `json:"orderedAt"`
+	Recipients  []string `json:"recipients"`
+	DeliveredAt int64    `json:"deliveredAt,omitempty"`
+	PickedUp    []string `json:"pickedUp,omitempty"`
+	RemindedAt  int64    `json:"remindedAt,omitempty"`
+}
+
+// deliveryRecipients returns the voters who take part in the order: everyone
+// who ordered from the menu, or everyone who picked the first option, which is
+// "Yes" for polls without options.
+func deliveryRecipients(poll Poll) []string {
+	recipients := []string{}
+	if len(poll.Spec.Menu) > 0 {
+		for _, person := range buildOrderSheet(poll.Spec.Menu, poll.Status.Voters).People {
+			recipients = append(recipients, person.Name)
+		}
+		return recipients
+	}
+	first := pollOptions(poll.Spec.Options)[0].Value
+	for _, voter := range poll.Status.Voters {
+		for _, choice := range voter.choices() {
+			if strings.EqualFold(choice, first) {
+				recipients = append(recipients, voter.Name)
+				break
+			}
+		}
+	}
+	return recipients
+}
+
+// pendingPickup returns the recipients who haven't confirmed the pickup yet.
+func pendingPickup(delivery Delivery) []string {
+	pickedUp := make(map[string]bool, len(delivery.PickedUp))
+	for _, name := range delivery.PickedUp {
+		pickedUp[name] = true
+	}
+	pending := make([]string, 0, len(delivery.Recipients))
+	for _, name := range delivery.Recipients {
+		if !pickedUp[name] {
+			pending = append(pending, name)
+		}
+	}
+	return pending
+}
+
+// AwaitsPickup checks if recipients of the poll's order can still confirm the
+// pickup: some of them haven't confirmed it yet.
+func AwaitsPickup(xr *resource.Composite) bool {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return false
+	}
+	delivery := poll.Status.Delivery
+	return delivery != nil && len(pendingPickup(*delivery)) > 0
+}
+
+// notifyPickup sends a DM with a "Picked up" button to each of the users.
+func notifyPickup(api Messenger, channelID, pollName, text string, users []string, logger logging.Logger) error {
+	ids, err := slackUserIDs(api, channelID)
+	if err != nil {
+		return fmt.Errorf("cannot get conversation members: %w", err)
+	}
+	attachment := slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: pollName,
+		Title:      pollTitle,
+		Text:       text,
+		Actions:    []slack.AttachmentAction{{Name: "actionPickedUp", Text: "Picked up", Type: "button", Style: "primary"}},
+	}
+	for _, name := range users {
+		userID, ok := ids[name]
+		if !ok {
+			continue
+		}
+		if _, _, err := api.PostMessage(userID, slack.MsgOptionText("", false), slack.MsgOptionAttachments(attachment), slack.MsgOptionAsUser(true)); err != nil {
+			logger.Info("error sending pickup message", "warning", err, "user", name)
+		}
+	}
+	return nil
+}
+
+// TrackDelivery tells the recipients that the food has arrived once
+// spec.deliveryTime seconds have passed since the order was placed, and
+// reminds those who haven't confirmed the pickup spec.dueTakeTime seconds
+// after that. Slack API failures are returned so that they are retried.
+func TrackDelivery(api Messenger, channelID string, xr *resource.Composite, currentTimestamp int, logger logging.Logger) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return fmt.Errorf("cannot convert composite to Poll: %w", err)
+	}
+	delivery := poll.Status.Delivery
+	if delivery == nil || delivery.RemindedAt != 0 {
+		return nil
+	}
+	pollTitle = poll.Spec.Title
+	now := int64(currentTimestamp)
+	deliveredAt := delivery.OrderedAt + poll.Spec.DeliveryTime
+
+	switch {
+	case delivery.DeliveredAt == 0 && now >= deliveredAt:
+		text := poll.Spec.Messages.Delivery
+		if text == "" {
+			text = defaultDeliveryText
+		}
+		if err := notifyPickup(api, channelID, pollRef(poll), text, delivery.Recipients, logger); err != nil {
+			return err
+		}
+		delivery.DeliveredAt = now
+		if poll.Spec.DueTakeTime == 0 {
+			// Without a pickup deadline there is nothing left to remind about.
+			delivery.RemindedAt = now
+		}
+	case delivery.DeliveredAt != 0 && now >= deliveredAt+poll.Spec.DueTakeTime:
+		text := poll.Spec.Messages.PickupReminder
+		if text == "" {
+			text = defaultPickupReminderText
+		}
+		if err := notifyPickup(api, channelID, pollRef(poll), text, pendingPickup(*delivery), logger); err != nil {
+			return err
+		}
+		delivery.RemindedAt = now
+	default:
+		return nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(delivery)
+	if err != nil {
+		return fmt.Errorf("cannot convert Delivery to Unstructured: %w", err)
+	}
+	return xr.Resource.SetValue("status.delivery", u)
+}
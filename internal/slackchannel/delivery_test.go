@@ -0,0 +1,44 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeliveryRecipients(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		poll   func() Poll
+		want   []string
+	}{
+		"YesVoters": {
+			reason: "Polls without options should deliver to everyone who voted yes",
+			poll: func() Poll {
+				p := Poll{}
+				p.Status.Voters = []Voter{{Name: "a", Status: "Yes"}, {Name: "b", Status: "No"}, {Name: "c", Status: "yes"}}
+				return p
+			},
+			want: []string{"a", "c"},
+		},
+		"MenuOrders": {
+			reason: "Meal polls should deliver to everyone who ordered",
+			poll: func() Poll {
+				p := Poll{}
+				p.Spec.Menu = []MenuItem{{ID: "pizza", Name: "Pizza", Price: 10}}
+				p.Status.Voters = []Voter{{Name: "b", Order: map[string]int64{"pizza": 1}}, {Name: "a", Order: map[string]int64{"pizza": 0}}}
+				return p
+			},
+			want: []string{"b"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := deliveryRecipients(tc.poll())
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ndeliveryRecipients(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
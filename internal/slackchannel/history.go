@@ -0,0 +1,118 @@
+package slackchannel
+
+import (
+	"strconv"
+
+	"github.com/crossplane/function-template-go/internal/rankedchoice"
+)
+
+// defaultRetention is the number of rounds kept when spec.history.retention
+// is not set.
+const defaultRetention = 10
+
+// History configures how many past rounds are kept in status.rounds and
+// whether voter names are stored with them.
+type History struct {
+	Retention int64 `json:"retention,omitempty"`
+	Anonymize bool  `json:"anonymize,omitempty"`
+}
+
+// RoundTally is the number of votes an option got in a round. Question is
+// set for multi-question polls; for meal polls Option is the menu item and
+// Votes the ordered quantity.
+type RoundTally struct {
+	Question string `json:"question,omitempty"`
+	Option   string `json:"option"`
+	Votes    int64  `json:"votes"`
+}
+
+// Round is the outcome of a single poll round.
+type Round struct {
+	ID            string       `json:"id,omitempty"`
+	StartedAt     int64        `json:"startedAt"`
+	ClosedAt      int64        `json:"closedAt"`
+	Tallies       []RoundTally `json:"tallies"`
+	Participation int64        `json:"participation"`
+	Voters        []Voter      `json:"voters"`
+}
+
+// roundTallies counts the votes of the round in the way the results were
+// posted.
+func roundTallies(poll Poll) []RoundTally {
+	tallies := []RoundTally{}
+	options := pollOptions(poll.Spec.Options)
+	switch {
+	case len(poll.Spec.Menu) > 0:
+		for _, line := range buildOrderSheet(poll.Spec.Menu, poll.Status.Voters).Items {
+			tallies = append(tallies, RoundTally{Option: line.ID, Votes: line.Quantity})
+		}
+	case len(poll.Spec.Questions) > 0:
+		for _, question := range poll.Spec.Questions {
+			answers := make([]Voter, 0, len(poll.Status.Voters))
+			for _, voter := range poll.Status.Voters {
+				answers = append(answers, Voter{Name: voter.Name, Status: voter.Answers[question.ID]})
+			}
+			questionOptions := pollOptions(question.Options)
+			for i, count := range countVotes(answers, questionOptions) {
+				tallies = append(tallies, RoundTally{Question: question.ID, Option: questionOptions[i].Value, Votes: int64(count)})
+			}
+		}
+	case poll.Spec.Answer.Type == "number", poll.Spec.Answer.Type == "text":
+		// Free-text and number answers have no tallies.
+	case poll.Spec.Method == "rankedChoice":
+		candidates := make([]string, 0, len(options))
+		for _, option := range options {
+			candidates = append(candidates, option.Value)
+		}
+		ballots := make([][]string, 0, len(poll.Status.Voters))
+		for _, voter := range poll.Status.Voters {
+			ballots = append(ballots, voter.choices())
+		}
+		result := rankedchoice.InstantRunoff(candidates, ballots)
+		if len(result.Rounds) > 0 {
+			for _, tally := range result.Rounds[len(result.Rounds)-1].Tallies {
+				tallies = append(tallies, RoundTally{Option: tally.Candidate, Votes: int64(tally.Votes)})
+			}
+		}
+	default:
+		for i, count := range countVotes(poll.Status.Voters, options) {
+			tallies = append(tallies, RoundTally{Option: options[i].Value, Votes: int64(count)})
+		}
+	}
+	return tallies
+}
+
+// recordRound appends the current round to status.rounds, dropping the
+// oldest rounds beyond the retention count.
+func recordRound(poll *Poll) {
+	round := Round{
+		ID:        poll.Status.Round,
+		StartedAt: poll.Status.PhaseTimestamps.Open,
+		ClosedAt:  poll.Status.PhaseTimestamps.Closed,
+		Tallies:   roundTallies(*poll),
+		Voters:    []Voter{},
+	}
+	if round.StartedAt == 0 {
+		round.StartedAt = poll.Status.LastNotificationTime
+	}
+	for _, voter := range poll.Status.Voters {
+		if !voter.voted() {
+			continue
+		}
+		round.Participation++
+		if poll.Spec.History.Anonymize {
+			voter.Name = "voter-" + strconv.FormatInt(round.Participation, 10)
+		}
+		round.Voters = append(round.Voters, voter)
+	}
+
+	retention := poll.Spec.History.Retention
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	rounds := append(poll.Status.Rounds, round)
+	if int64(len(rounds)) > retention {
+		rounds = rounds[int64(len(rounds))-retention:]
+	}
+	poll.Status.Rounds = rounds
+}
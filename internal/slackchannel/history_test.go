@@ -0,0 +1,67 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordRound(t *testing.T) {
+	poll := func(history History, rounds []Round, voters []Voter) Poll {
+		p := Poll{}
+		p.Spec.History = history
+		p.Status.Voters = voters
+		p.Status.LastNotificationTime = 10
+		p.Status.PhaseTimestamps = PhaseTimestamps{Open: 10, Closed: 20}
+		p.Status.Rounds = rounds
+		return p
+	}
+	voters := []Voter{{Name: "alice", Status: "yes"}, {Name: "bob"}, {Name: "carol", Status: "no"}}
+
+	cases := map[string]struct {
+		reason string
+		poll   Poll
+		want   []Round
+	}{
+		"Tally": {
+			reason: "The round should hold the tallies and the voters who voted",
+			poll:   poll(History{}, nil, voters),
+			want: []Round{{
+				StartedAt:     10,
+				ClosedAt:      20,
+				Tallies:       []RoundTally{{Option: "Yes", Votes: 1}, {Option: "No", Votes: 1}},
+				Participation: 2,
+				Voters:        []Voter{{Name: "alice", Status: "yes"}, {Name: "carol", Status: "no"}},
+			}},
+		},
+		"Anonymize": {
+			reason: "Voter names should be replaced when the history is anonymized",
+			poll:   poll(History{Anonymize: true}, nil, voters),
+			want: []Round{{
+				StartedAt:     10,
+				ClosedAt:      20,
+				Tallies:       []RoundTally{{Option: "Yes", Votes: 1}, {Option: "No", Votes: 1}},
+				Participation: 2,
+				Voters:        []Voter{{Name: "voter-1", Status: "yes"}, {Name: "voter-2", Status: "no"}},
+			}},
+		},
+		"Retention": {
+			reason: "The oldest rounds should be dropped beyond the retention count",
+			poll:   poll(History{Retention: 2}, []Round{{StartedAt: 1}, {StartedAt: 2}}, nil),
+			want: []Round{
+				{StartedAt: 2},
+				{StartedAt: 10, ClosedAt: 20, Tallies: []RoundTally{{Option: "Yes"}, {Option: "No"}}, Voters: []Voter{}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.poll
+			recordRound(&got)
+			if diff := cmp.Diff(tc.want, got.Status.Rounds); diff != "" {
+				t.Errorf("%s\nrecordRound(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
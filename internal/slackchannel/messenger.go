@@ -0,0 +1,13 @@
+package slackchannel
+
+import "github.com/slack-go/slack"
+
+// Messenger is the part of the Slack Web API the function uses. It's
+// implemented by *slack.Client.
+type Messenger interface {
+	GetConversations(params *slack.GetConversationsParameters) ([]slack.Channel, string, error)
+	GetUsersInConversation(params *slack.GetUsersInConversationParameters) ([]string, string, error)
+	GetUserInfo(user string) (*slack.User, error)
+	GetConversationHistory(params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error)
+	PostMessage(channelID string, options ...slack.MsgOption) (string, string, error)
+}
@@ -0,0 +1,120 @@
+package slackchannel
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Amount is a sum of money in a currency.
+type Amount struct {
+	Value    float64 `json:"value"`
+	Currency string  `json:"currency,omitempty"`
+}
+
+// OrderLine is the ordered quantity of a menu item.
+type OrderLine struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Quantity int64  `json:"quantity"`
+	Amount   Amount `json:"amount"`
+}
+
+// PersonOrder is everything a single voter ordered.
+type PersonOrder struct {
+	Name  string      `json:"name"`
+	Items []OrderLine `json:"items"`
+	Total []Amount    `json:"total"`
+}
+
+// OrderSheet aggregates the orders of all voters of a meal poll.
+type OrderSheet struct {
+	Items  []OrderLine   `json:"items"`
+	People []PersonOrder `json:"people"`
+	Total  []Amount      `json:"total"`
+}
+
+// addAmount adds an amount to the running totals, keeping one total per currency.
+func addAmount(totals []Amount, amount Amount) []Amount {
+	for i := range totals {
+		if totals[i].Currency == amount.Currency {
+			totals[i].Value += amount.Value
+			return totals
+		}
+	}
+	return append(totals, amount)
+}
+
+// buildOrderSheet aggregates the orders of the voters in menu order. Items
+// that are not on the menu and non-positive quantities are ignored.
+func buildOrderSheet(menu []MenuItem, voters []Voter) OrderSheet {
+	sheet := OrderSheet{Items: []OrderLine{}, People: []PersonOrder{}, Total: []Amount{}}
+	quantities := make(map[string]int64, len(menu))
+	for _, voter := range voters {
+		person := PersonOrder{Name: voter.Name, Items: []OrderLine{}, Total: []Amount{}}
+		for _, item := range menu {
+			quantity := voter.Order[item.ID]
+			if quantity <= 0 {
+				continue
+			}
+			line := OrderLine{ID: item.ID, Name: item.Name, Quantity: quantity, Amount: Amount{Value: item.Price * float64(quantity), Currency: item.Currency}}
+			person.Items = append(person.Items, line)
+			person.Total = addAmount(person.Total, line.Amount)
+			quantities[item.ID] += quantity
+		}
+		if len(person.Items) > 0 {
+			sheet.People = append(sheet.People, person)
+		}
+	}
+	sort.SliceStable(sheet.People, func(i, j int) bool { return sheet.People[i].Name < sheet.People[j].Name })
+
+	for _, item := range menu {
+		quantity := quantities[item.ID]
+		if quantity == 0 {
+			continue
+		}
+		line := OrderLine{ID: item.ID, Name: item.Name, Quantity: quantity, Amount: Amount{Value: item.Price * float64(quantity), Currency: item.Currency}}
+		sheet.Items = append(sheet.Items, line)
+		sheet.Total = addAmount(sheet.Total, line.Amount)
+	}
+	return sheet
+}
+
+// formatAmounts renders amounts such as "12.50 MDL + 3.00 EUR".
+func formatAmounts(amounts []Amount) string {
+	if len(amounts) == 0 {
+		return "0.00"
+	}
+	parts := make([]string, 0, len(amounts))
+	for _, amount := range amounts {
+		parts = append(parts, strings.TrimSpace(strconv.FormatFloat(amount.Value, 'f', 2, 64)+" "+amount.Currency))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// formatOrderLines renders order lines such as "2 × Pizza, 1 × Cola".
+func formatOrderLines(lines []OrderLine) string {
+	parts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		parts = append(parts, strconv.FormatInt(line.Quantity, 10)+" × "+line.Name)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatOrderSheet renders the order sheet with per-item quantities, a
+// per-person list and the grand total.
+func formatOrderSheet(sheet OrderSheet) string {
+	if len(sheet.Items) == 0 {
+		return "Nobody ordered anything."
+	}
+	lines := []string{"*Items*"}
+	for _, item := range sheet.Items {
+		lines = append(lines, "• "+formatOrderLines([]OrderLine{item})+" — "+formatAmounts([]Amount{item.Amount}))
+	}
+	lines = append(lines, "", "*Per person*")
+	for _, person := range sheet.People {
+		lines = append(lines, "• "+person.Name+": "+formatOrderLines(person.Items)+" — "+formatAmounts(person.Total))
+	}
+	lines = append(lines, "", "*Total:* "+formatAmounts(sheet.Total))
+	return strings.Join(lines, "\n")
+}
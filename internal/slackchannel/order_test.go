@@ -0,0 +1,76 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildOrderSheet(t *testing.T) {
+	menu := []MenuItem{
+		{ID: "pizza", Name: "Pizza", Price: 12.5, Currency: "MDL"},
+		{ID: "cola", Name: "Cola", Price: 2, Currency: "MDL"},
+		{ID: "soup", Name: "Soup", Price: 7, Currency: "MDL"},
+	}
+
+	type args struct {
+		menu   []MenuItem
+		voters []Voter
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   OrderSheet
+	}{
+		"NoOrders": {
+			reason: "Voters without orders should result in an empty sheet",
+			args: args{
+				menu:   menu,
+				voters: []Voter{{Name: "a", Status: "Yes"}},
+			},
+			want: OrderSheet{Items: []OrderLine{}, People: []PersonOrder{}, Total: []Amount{}},
+		},
+		"Orders": {
+			reason: "Quantities should be aggregated per item and per person, ignoring unknown items",
+			args: args{
+				menu: menu,
+				voters: []Voter{
+					{Name: "bob", Order: map[string]int64{"pizza": 1, "cola": 2}},
+					{Name: "alice", Order: map[string]int64{"pizza": 2, "burger": 1, "soup": 0}},
+				},
+			},
+			want: OrderSheet{
+				Items: []OrderLine{
+					{ID: "pizza", Name: "Pizza", Quantity: 3, Amount: Amount{Value: 37.5, Currency: "MDL"}},
+					{ID: "cola", Name: "Cola", Quantity: 2, Amount: Amount{Value: 4, Currency: "MDL"}},
+				},
+				People: []PersonOrder{
+					{
+						Name:  "alice",
+						Items: []OrderLine{{ID: "pizza", Name: "Pizza", Quantity: 2, Amount: Amount{Value: 25, Currency: "MDL"}}},
+						Total: []Amount{{Value: 25, Currency: "MDL"}},
+					},
+					{
+						Name: "bob",
+						Items: []OrderLine{
+							{ID: "pizza", Name: "Pizza", Quantity: 1, Amount: Amount{Value: 12.5, Currency: "MDL"}},
+							{ID: "cola", Name: "Cola", Quantity: 2, Amount: Amount{Value: 4, Currency: "MDL"}},
+						},
+						Total: []Amount{{Value: 16.5, Currency: "MDL"}},
+					},
+				},
+				Total: []Amount{{Value: 41.5, Currency: "MDL"}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := buildOrderSheet(tc.args.menu, tc.args.voters)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nbuildOrderSheet(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
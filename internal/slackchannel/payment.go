@@ -0,0 +1,195 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Payment configures how the cost of a meal order is split between voters.
+// Split is either perItem, where everyone pays for what they ordered, or even.
+// The delivery fee is always split evenly.
+type Payment struct {
+	Split              string  `json:"split,omitempty"`
+	DeliveryFee        float64 `json:"deliveryFee,omitempty"`
+	Currency           string  `json:"currency,omitempty"`
+	ReminderAfterHours int64   `json:"reminderAfterHours,omitempty"`
+	SettleAfterHours   int64   `json:"settleAfterHours,omitempty"`
+}
+
+// LedgerEntry is the share of a single voter.
+type LedgerEntry struct {
+	Name   string   `json:"name"`
+	Amount []Amount `json:"amount"`
+	Paid   bool     `json:"paid"`
+	PaidAt int64    `json:"paidAt,omitempty"`
+}
+
+// Ledger tracks the payments of a single round.
+type Ledger struct {
+	CreatedAt  int64         `json:"createdAt"`
+	Entries    []LedgerEntry `json:"entries"`
+	RemindedAt int64         `json:"remindedAt,omitempty"`
+	SettledAt  int64         `json:"settledAt,omitempty"`
+}
+
+// buildLedger splits the cost of the order sheet between the people who ordered.
+func buildLedger(payment Payment, sheet OrderSheet, now int64) Ledger {
+	ledger := Ledger{CreatedAt: now, Entries: []LedgerEntry{}}
+	n := float64(len(sheet.People))
+	if n == 0 {
+		return ledger
+	}
+	currency := payment.Currency
+	if currency == "" && len(sheet.Total) > 0 {
+		currency = sheet.Total[0].Currency
+	}
+
+	evenShare := []Amount{}
+	if payment.Split == "even" {
+		for _, total := range sheet.Total {
+			evenShare = append(evenShare, Amount{Value: total.Value / n, Currency: total.Currency})
+		}
+	}
+	for _, person := range sheet.People {
+		share := []Amount{}
+		if payment.Split == "even" {
+			share = append(share, evenShare...)
+		} else {
+			share = append(share, person.Total...)
+		}
+		if payment.DeliveryFee > 0 {
+			share = addAmount(share, Amount{Value: payment.DeliveryFee / n, Currency: currency})
+		}
+		ledger.Entries = append(ledger.Entries, LedgerEntry{Name: person.Name, Amount: share})
+	}
+	return ledger
+}
+
+// outstanding returns the entries of the ledger that are not paid yet.
+func outstanding(ledger Ledger) []LedgerEntry {
+	entries := make([]LedgerEntry, 0, len(ledger.Entries))
+	for _, entry := range ledger.Entries {
+		if !entry.Paid {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// formatLedger renders the share of every voter and whether they paid.
+func formatLedger(entries []LedgerEntry) string {
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		line := "• " + entry.Name + ": " + formatAmounts(entry.Amount)
+		if entry.Paid {
+			line += " (paid)"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatSettlement renders the outstanding balances of the ledger.
+func formatSettlement(ledger Ledger) string {
+	unpaid := outstanding(ledger)
+	if len(unpaid) == 0 {
+		return "Everyone has paid."
+	}
+	return "Outstanding balances:\n" + formatLedger(unpaid)
+}
+
+// paymentAttachment lists the shares of the ledger with a button to mark them as paid.
+func paymentAttachment(pollName string, ledger Ledger) slack.Attachment {
+	return slack.Attachment{
+		Color:      "#f9a41b",
+		CallbackID: pollName,
+		Title:      "Payments",
+		Text:       formatLedger(ledger.Entries),
+		Actions:    []slack.AttachmentAction{{Name: "actionPaid", Text: "Mark as paid", Type: "button", Style: "primary"}},
+	}
+}
+
+// remindUnpaid sends a reminder DM to every voter who hasn't paid yet.
+func remindUnpaid(api Messenger, channelID, pollName string, ledger Ledger, logger logging.Logger) error {
+	ids, err := slackUserIDs(api, channelID)
+	if err != nil {
+		return fmt.Errorf("cannot get conversation members: %w", err)
+	}
+	for _, entry := range outstanding(ledger) {
+		userID, ok := ids[entry.Name]
+		if !ok {
+			continue
+		}
+		attachment := paymentAttachment(pollName, Ledger{Entries: []LedgerEntry{entry}})
+		attachment.Title = pollTitle
+		attachment.Text = "Reminder: you still owe " + formatAmounts(entry.Amount) + " for your order."
+		if _, _, err := api.PostMessage(userID, slack.MsgOptionText("", false), slack.MsgOptionAttachments(attachment), slack.MsgOptionAsUser(true)); err != nil {
+			logger.Info("error sending payment reminder", "warning", err, "user", entry.Name)
+		}
+	}
+	return nil
+}
+
+// AwaitsPayment checks if voters can still mark their share of the poll as
+// paid: its ledger isn't settled and some entries aren't paid yet.
+func AwaitsPayment(xr *resource.Composite) bool {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return false
+	}
+	ledger := poll.Status.Ledger
+	return ledger != nil && ledger.SettledAt == 0 && len(outstanding(*ledger)) > 0
+}
+
+// TrackPayments reminds voters who haven't paid after the configured number
+// of hours and posts a final settlement message once everyone has paid or the
+// settlement deadline has passed. Slack API failures are returned so that
+// they are retried.
+func TrackPayments(api Messenger, channelID string, xr *resource.Composite, currentTimestamp int, logger logging.Logger) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return fmt.Errorf("cannot convert composite to Poll: %w", err)
+	}
+	ledger := poll.Status.Ledger
+	if poll.Spec.Payment == nil || ledger == nil || ledger.SettledAt != 0 {
+		return nil
+	}
+	pollTitle = poll.Spec.Title
+	now := int64(currentTimestamp)
+	elapsed := time.Duration(now-ledger.CreatedAt) * time.Second
+	payment := poll.Spec.Payment
+
+	switch {
+	case len(outstanding(*ledger)) == 0,
+		payment.SettleAfterHours > 0 && elapsed >= time.Duration(payment.SettleAfterHours)*time.Hour:
+		attachment := slack.Attachment{
+			Color: "#f9a41b",
+			Title: pollTitle,
+			Text:  formatSettlement(*ledger),
+		}
+		if _, _, err := api.PostMessage(channelID, slack.MsgOptionText("", false), slack.MsgOptionAttachments(attachment), slack.MsgOptionAsUser(true)); err != nil {
+			return fmt.Errorf("cannot send settlement message: %w", err)
+		}
+		ledger.SettledAt = now
+	case payment.ReminderAfterHours > 0 && ledger.RemindedAt == 0 && elapsed >= time.Duration(payment.ReminderAfterHours)*time.Hour:
+		if err := remindUnpaid(api, channelID, pollRef(poll), *ledger, logger); err != nil {
+			return err
+		}
+		ledger.RemindedAt = now
+	default:
+		return nil
+	}
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ledger)
+	if err != nil {
+		return fmt.Errorf("cannot convert Ledger to Unstructured: %w", err)
+	}
+	return xr.Resource.SetValue("status.ledger", u)
+}
@@ -0,0 +1,67 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuildLedger(t *testing.T) {
+	sheet := OrderSheet{
+		People: []PersonOrder{
+			{Name: "alice", Total: []Amount{{Value: 25, Currency: "MDL"}}},
+			{Name: "bob", Total: []Amount{{Value: 15, Currency: "MDL"}}},
+		},
+		Total: []Amount{{Value: 40, Currency: "MDL"}},
+	}
+
+	type args struct {
+		payment Payment
+		sheet   OrderSheet
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   Ledger
+	}{
+		"PerItem": {
+			reason: "Everyone should pay for what they ordered plus an even part of the delivery fee",
+			args: args{
+				payment: Payment{Split: "perItem", DeliveryFee: 10},
+				sheet:   sheet,
+			},
+			want: Ledger{CreatedAt: 100, Entries: []LedgerEntry{
+				{Name: "alice", Amount: []Amount{{Value: 30, Currency: "MDL"}}},
+				{Name: "bob", Amount: []Amount{{Value: 20, Currency: "MDL"}}},
+			}},
+		},
+		"Even": {
+			reason: "The total should be split evenly",
+			args: args{
+				payment: Payment{Split: "even"},
+				sheet:   sheet,
+			},
+			want: Ledger{CreatedAt: 100, Entries: []LedgerEntry{
+				{Name: "alice", Amount: []Amount{{Value: 20, Currency: "MDL"}}},
+				{Name: "bob", Amount: []Amount{{Value: 20, Currency: "MDL"}}},
+			}},
+		},
+		"NobodyOrdered": {
+			reason: "An empty order sheet should result in an empty ledger",
+			args: args{
+				payment: Payment{DeliveryFee: 10},
+			},
+			want: Ledger{CreatedAt: 100, Entries: []LedgerEntry{}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := buildLedger(tc.args.payment, tc.args.sheet, 100)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nbuildLedger(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
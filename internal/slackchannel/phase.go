@@ -0,0 +1,158 @@
+package slackchannel
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Phase is a step in the lifecycle of a poll round.
+type Phase string
+
+// Poll phases. A round goes from Open through Closing and Closed to
+// ResultsPosted, after which the next round opens it again. Organizers can
+// cancel a round before its results are posted.
+const (
+	PhasePending       Phase = "Pending"
+	PhaseOpen          Phase = "Open"
+	PhaseClosing       Phase = "Closing"
+	PhaseClosed        Phase = "Closed"
+	PhaseResultsPosted Phase = "ResultsPosted"
+	PhaseCancelled     Phase = "Cancelled"
+)
+
+// transitions lists the phases each phase can move to.
+var transitions = map[Phase][]Phase{
+	PhasePending:       {PhaseOpen},
+	PhaseOpen:          {PhaseClosing, PhaseCancelled},
+	PhaseClosing:       {PhaseClosed, PhaseCancelled},
+	PhaseClosed:        {PhaseResultsPosted, PhaseOpen, PhaseCancelled},
+	PhaseResultsPosted: {PhaseOpen},
+	PhaseCancelled:     {PhaseOpen},
+}
+
+// PhaseTimestamps records when the poll last entered each phase.
+type PhaseTimestamps struct {
+	Pending       int64 `json:"pending,omitempty"`
+	Open          int64 `json:"open,omitempty"`
+	Closing       int64 `json:"closing,omitempty"`
+	Closed        int64 `json:"closed,omitempty"`
+	ResultsPosted int64 `json:"resultsPosted,omitempty"`
+	Cancelled     int64 `json:"cancelled,omitempty"`
+}
+
+// canTransition checks if a poll can move from one phase to another.
+func canTransition(from, to Phase) bool {
+	for _, p := range transitions[from] {
+		if p == to {
+			return true
+		}
+	}
+	return false
+}
+
+// phaseOf returns the phase of a poll, deriving it from the done flag and the
+// last notification time for polls created before phases existed.
+func phaseOf(phase Phase, done bool, lastNotificationTime int64) Phase {
+	switch {
+	case phase != "":
+		return phase
+	case lastNotificationTime == 0:
+		return PhasePending
+	case done:
+		return PhaseResultsPosted
+	default:
+		return PhaseOpen
+	}
+}
+
+// phase returns the phase of the poll.
+func (p *Poll) phase() Phase {
+	return phaseOf(p.Status.Phase, p.Status.Done, p.Status.LastNotificationTime)
+}
+
+// setPhase moves the poll to a new phase and records when it happened.
+func (p *Poll) setPhase(to Phase, now int64) error {
+	from := p.phase()
+	if !canTransition(from, to) {
+		return fmt.Errorf("cannot move poll from phase %s to %s", from, to)
+	}
+	p.Status.Phase = to
+	p.Status.Done = to != PhasePending && to != PhaseOpen
+	switch to {
+	case PhasePending:
+		p.Status.PhaseTimestamps.Pending = now
+	case PhaseOpen:
+		p.Status.PhaseTimestamps.Open = now
+	case PhaseClosing:
+		p.Status.PhaseTimestamps.Closing = now
+	case PhaseClosed:
+		p.Status.PhaseTimestamps.Closed = now
+	case PhaseResultsPosted:
+		p.Status.PhaseTimestamps.ResultsPosted = now
+	case PhaseCancelled:
+		p.Status.PhaseTimestamps.Cancelled = now
+	}
+	return nil
+}
+
+// GetPhase returns the phase of the poll composite resource.
+func GetPhase(xr *resource.Composite) Phase {
+	phase, _ := xr.Resource.GetString("status.phase")
+	done, _ := xr.Resource.GetBool("status.done")
+	lastNotificationTime, _ := xr.Resource.GetInteger("status.lastNotificationTime")
+	return phaseOf(Phase(phase), done, lastNotificationTime)
+}
+
+// SetPhase moves the poll composite resource to a new phase, rejecting
+// transitions the lifecycle doesn't allow.
+func SetPhase(xr *resource.Composite, to Phase, currentTimestamp int) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return err
+	}
+	if err := poll.setPhase(to, int64(currentTimestamp)); err != nil {
+		return err
+	}
+	timestamps, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&poll.Status.PhaseTimestamps)
+	if err != nil {
+		return err
+	}
+	if err := xr.Resource.SetString("status.phase", string(poll.Status.Phase)); err != nil {
+		return err
+	}
+	if err := xr.Resource.SetBool("status.done", poll.Status.Done); err != nil {
+		return err
+	}
+	return xr.Resource.SetValue("status.phaseTimestamps", timestamps)
+}
+
+// reopenedByNotifier checks if the notifier opened a round after the poll
+// finished the previous one. The status the function applies from an older
+// copy of the poll can put the finished round back over the one the notifier
+// opened meanwhile.
+func (p *Poll) reopenedByNotifier() bool {
+	var finishedAt int64
+	switch p.Status.Phase {
+	case PhaseResultsPosted:
+		finishedAt = p.Status.PhaseTimestamps.ResultsPosted
+	case PhaseCancelled:
+		finishedAt = p.Status.PhaseTimestamps.Cancelled
+	}
+	return finishedAt != 0 && p.Status.LastNotificationTime > finishedAt
+}
+
+// RestoreOpenedRound opens the round the notifier opened again, if the poll
+// was put back to the round it finished before. It returns whether it did.
+func RestoreOpenedRound(xr *resource.Composite) (bool, error) {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return false, err
+	}
+	if !poll.reopenedByNotifier() {
+		return false, nil
+	}
+	return true, SetPhase(xr, PhaseOpen, int(poll.Status.LastNotificationTime))
+}
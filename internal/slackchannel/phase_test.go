@@ -0,0 +1,198 @@
+package slackchannel
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetPhase(t *testing.T) {
+	poll := func(phase Phase, done bool, lastNotificationTime int64, timestamps PhaseTimestamps) Poll {
+		p := Poll{}
+		p.Status.Phase = phase
+		p.Status.Done = done
+		p.Status.LastNotificationTime = lastNotificationTime
+		p.Status.PhaseTimestamps = timestamps
+		return p
+	}
+
+	type args struct {
+		poll Poll
+		to   Phase
+	}
+	type want struct {
+		poll Poll
+		err  bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"OpenToClosing": {
+			reason: "An open poll should move to Closing and be marked as done",
+			args: args{
+				poll: poll(PhaseOpen, false, 50, PhaseTimestamps{}),
+				to:   PhaseClosing,
+			},
+			want: want{
+				poll: poll(PhaseClosing, true, 50, PhaseTimestamps{Closing: 100}),
+			},
+		},
+		"LegacyOpen": {
+			reason: "A poll without a phase that was notified and isn't done should be treated as Open",
+			args: args{
+				poll: poll("", false, 50, PhaseTimestamps{}),
+				to:   PhaseClosing,
+			},
+			want: want{
+				poll: poll(PhaseClosing, true, 50, PhaseTimestamps{Closing: 100}),
+			},
+		},
+		"ResultsPostedToOpen": {
+			reason: "A poll should open again for the next round",
+			args: args{
+				poll: poll(PhaseResultsPosted, true, 50, PhaseTimestamps{}),
+				to:   PhaseOpen,
+			},
+			want: want{
+				poll: poll(PhaseOpen, false, 50, PhaseTimestamps{Open: 100}),
+			},
+		},
+		"PendingToClosed": {
+			reason: "A poll that never opened cannot be closed",
+			args: args{
+				poll: poll(PhasePending, false, 0, PhaseTimestamps{}),
+				to:   PhaseClosed,
+			},
+			want: want{
+				poll: poll(PhasePending, false, 0, PhaseTimestamps{}),
+				err:  true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.args.poll
+			err := got.setPhase(tc.args.to, 100)
+			if diff := cmp.Diff(tc.want.err, err != nil); diff != "" {
+				t.Errorf("%s\nsetPhase(...): -want error, +got error:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.poll, got); diff != "" {
+				t.Errorf("%s\nsetPhase(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReopenedByNotifier(t *testing.T) {
+	poll := func(phase Phase, lastNotificationTime int64, timestamps PhaseTimestamps) Poll {
+		p := Poll{}
+		p.Status.Phase = phase
+		p.Status.LastNotificationTime = lastNotificationTime
+		p.Status.PhaseTimestamps = timestamps
+		return p
+	}
+
+	cases := map[string]struct {
+		reason string
+		poll   Poll
+		want   bool
+	}{
+		"ResultsPosted": {
+			reason: "A poll whose results were posted after its round opened is finished",
+			poll:   poll(PhaseResultsPosted, 100, PhaseTimestamps{Open: 100, ResultsPosted: 200}),
+			want:   false,
+		},
+		"OpenedAfterResults": {
+			reason: "A round the notifier opened after the results were posted should be restored",
+			poll:   poll(PhaseResultsPosted, 300, PhaseTimestamps{Open: 300, ResultsPosted: 200}),
+			want:   true,
+		},
+		"OpenedAfterCancel": {
+			reason: "A round the notifier opened after the previous one was cancelled should be restored",
+			poll:   poll(PhaseCancelled, 300, PhaseTimestamps{Open: 100, Cancelled: 200}),
+			want:   true,
+		},
+		"Legacy": {
+			reason: "Polls finished before phases were timestamped can't tell and should be left alone",
+			poll:   poll(PhaseResultsPosted, 300, PhaseTimestamps{}),
+			want:   false,
+		},
+		"Open": {
+			reason: "Open polls don't need to be restored",
+			poll:   poll(PhaseOpen, 300, PhaseTimestamps{Open: 300, ResultsPosted: 200}),
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.poll.reopenedByNotifier()
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nreopenedByNotifier(): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// phaseDeclarations returns the source of the phases, their transitions and
+// their timestamps declared in a file, by name.
+func phaseDeclarations(t *testing.T, path string) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := map[string]string{}
+	add := func(name string, node ast.Node) {
+		src := &strings.Builder{}
+		if err := format.Node(src, fset, node); err != nil {
+			t.Fatal(err)
+		}
+		decls[name] = src.String()
+	}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			switch spec := spec.(type) {
+			case *ast.TypeSpec:
+				if spec.Name.Name == "Phase" || spec.Name.Name == "PhaseTimestamps" {
+					add(spec.Name.Name, spec)
+				}
+			case *ast.ValueSpec:
+				name := spec.Names[0].Name
+				if strings.HasPrefix(name, "Phase") || name == "transitions" {
+					add(name, spec)
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// TestPhaseCopies checks that the notifier and the collector, which are
+// separate modules, move polls through the same phases as the function.
+func TestPhaseCopies(t *testing.T) {
+	want := phaseDeclarations(t, "phase.go")
+	for _, path := range []string{
+		filepath.Join("..", "slack-notify", "phase.go"),
+		filepath.Join("..", "slack-collector", "phase.go"),
+	} {
+		if diff := cmp.Diff(want, phaseDeclarations(t, path)); diff != "" {
+			t.Errorf("%s: the phases differ from the ones of the function: -want, +got:\n%s", path, diff)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package slackchannel
+
+// pollRef returns the reference of the poll carried in Slack callback IDs:
+// the namespace and name of the claim for claimed polls, or the name of the
+// poll.
+func pollRef(poll Poll) string {
+	if poll.Spec.ClaimRef != nil {
+		return poll.Spec.ClaimRef.Namespace + "/" + poll.Spec.ClaimRef.Name
+	}
+	return poll.GetName()
+}
@@ -0,0 +1,120 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// Reminders configures follow-up messages to channel members who haven't
+// voted. Offsets are durations before the close time of the round.
+type Reminders struct {
+	Offsets []string `json:"offsets,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ReminderStatus tracks the reminders sent in a round.
+type ReminderStatus struct {
+	Round  string   `json:"round,omitempty"`
+	Sent   []string `json:"sent,omitempty"`
+	SentAt int64    `json:"sentAt,omitempty"`
+}
+
+// dueReminders returns the offsets that are due at now and weren't sent yet.
+func dueReminders(offsets, sent []string, closeTime, now int64) ([]string, error) {
+	done := make(map[string]bool, len(sent))
+	for _, offset := range sent {
+		done[offset] = true
+	}
+	due := []string{}
+	for _, offset := range offsets {
+		d, err := ParseDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reminder offset: %w", err)
+		}
+		if !done[offset] && now >= closeTime-int64(d/time.Second) {
+			due = append(due, offset)
+		}
+	}
+	return due, nil
+}
+
+// formatRemaining formats the time left until the poll closes in minutes.
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Minute)
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
+
+// remindNonVoters sends the reminder to the channel members who haven't voted
+// in the current round.
+func remindNonVoters(api Messenger, channelID, text string, voters []Voter, logger logging.Logger) error {
+	ids, err := slackUserIDs(api, channelID)
+	if err != nil {
+		return fmt.Errorf("cannot get conversation members: %w", err)
+	}
+	for name, userID := range ids {
+		if !UserVoted(voters, name) {
+			continue
+		}
+		attachment := slack.Attachment{
+			Color: "#f9a41b",
+			Title: pollTitle,
+			Text:  text,
+		}
+		if _, _, err := api.PostMessage(userID, slack.MsgOptionText("", false), slack.MsgOptionAttachments(attachment), slack.MsgOptionAsUser(true)); err != nil {
+			logger.Info("error sending vote reminder", "warning", err, "user", name)
+		}
+	}
+	return nil
+}
+
+// TrackReminders reminds the channel members who haven't voted once an offset
+// of spec.reminders before the close time is reached. Reminders due at the
+// same time are sent as one message, sent reminders are recorded in
+// status.reminders so they go out once per round.
+func TrackReminders(api Messenger, channelID string, xr *resource.Composite, currentTimestamp int, logger logging.Logger) error {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		return fmt.Errorf("cannot convert composite to Poll: %w", err)
+	}
+	now := int64(currentTimestamp)
+	closeTime := poll.Status.CloseTime
+	if len(poll.Spec.Reminders.Offsets) == 0 || GetPhase(xr) != PhaseOpen || closeTime == 0 || now >= closeTime {
+		return nil
+	}
+	round := currentRound(poll)
+	reminders := ReminderStatus{Round: round}
+	if poll.Status.Reminders != nil && poll.Status.Reminders.Round == round {
+		reminders = *poll.Status.Reminders
+	}
+	due, err := dueReminders(poll.Spec.Reminders.Offsets, reminders.Sent, closeTime, now)
+	if err != nil || len(due) == 0 {
+		return err
+	}
+
+	pollTitle = poll.Spec.Title
+	text := poll.Spec.Reminders.Message
+	if text == "" {
+		text = "Reminder: the poll closes in " + formatRemaining(time.Duration(closeTime-now)*time.Second) + " and you haven't voted yet."
+	}
+	if err := remindNonVoters(api, channelID, text, poll.Status.Voters, logger); err != nil {
+		return err
+	}
+	reminders.Sent = append(reminders.Sent, due...)
+	reminders.SentAt = now
+
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&reminders)
+	if err != nil {
+		return fmt.Errorf("cannot convert ReminderStatus to Unstructured: %w", err)
+	}
+	return xr.Resource.SetValue("status.reminders", u)
+}
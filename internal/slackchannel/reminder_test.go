@@ -0,0 +1,165 @@
+package slackchannel
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+	"github.com/crossplane/function-template-go/internal/slacktest"
+)
+
+func TestDueReminders(t *testing.T) {
+	const closeTime = 10000
+
+	type args struct {
+		offsets []string
+		sent    []string
+		now     int64
+	}
+	type want struct {
+		due []string
+		err bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"NotYet": {
+			reason: "No reminder should be due before its offset",
+			args:   args{offsets: []string{"30m", "5m"}, now: closeTime - 3600},
+			want:   want{due: []string{}},
+		},
+		"Due": {
+			reason: "A reminder should be due once its offset before the close time is reached",
+			args:   args{offsets: []string{"30m", "5m"}, now: closeTime - 1800},
+			want:   want{due: []string{"30m"}},
+		},
+		"Sent": {
+			reason: "Reminders that were sent in the round should not be due again",
+			args:   args{offsets: []string{"30m", "5m"}, sent: []string{"30m"}, now: closeTime - 600},
+			want:   want{due: []string{}},
+		},
+		"Missed": {
+			reason: "All reminders whose offset passed should be due together",
+			args:   args{offsets: []string{"30m", "PT5M"}, now: closeTime - 60},
+			want:   want{due: []string{"30m", "PT5M"}},
+		},
+		"Invalid": {
+			reason: "Invalid offsets should be rejected",
+			args:   args{offsets: []string{"soon"}, now: closeTime - 60},
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			due, err := dueReminders(tc.args.offsets, tc.args.sent, closeTime, tc.args.now)
+			if diff := cmp.Diff(tc.want, want{due: due, err: err != nil}, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\ndueReminders(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTrackReminders(t *testing.T) {
+	const closeTime = 10000
+
+	srv := slacktest.NewServer(
+		slacktest.User("U1", "alice"),
+		slacktest.User("U2", "bob"),
+		slacktest.User("U3", "carol"),
+		slacktest.Bot("B1", "pollbot"),
+	)
+	defer srv.Close()
+
+	poll := func(reminders string) *resource.Composite {
+		xr := &resource.Composite{Resource: composite.New()}
+		if err := resource.AsObject(resource.MustStructJSON(`{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "lunch"},
+			"spec": {"title": "lunch", "reminders": {"offsets": ["30m", "5m"]}},
+			"status": {
+				"phase": "Open",
+				"round": "100",
+				"closeTime": 10000,
+				"voters": [{"name": "alice", "status": "Yes"}, {"name": "bob", "status": ""}]`+reminders+`
+			}
+		}`), xr.Resource); err != nil {
+			t.Fatal(err)
+		}
+		return xr
+	}
+
+	type want struct {
+		reminded []string
+		sent     []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		xr     *resource.Composite
+		now    int
+		want   want
+	}{
+		"NotDue": {
+			reason: "Nobody should be reminded before the first offset",
+			xr:     poll(""),
+			now:    closeTime - 3600,
+			want:   want{reminded: []string{}},
+		},
+		"NonVoters": {
+			reason: "Members who haven't voted should be reminded, not the voters and the bots",
+			xr:     poll(""),
+			now:    closeTime - 1800,
+			want:   want{reminded: []string{"U2", "U3"}, sent: []string{"30m"}},
+		},
+		"SentInRound": {
+			reason: "Reminders sent in the round shouldn't be sent again",
+			xr:     poll(`, "reminders": {"round": "100", "sent": ["30m"], "sentAt": 8200}`),
+			now:    closeTime - 1200,
+			want:   want{reminded: []string{}, sent: []string{"30m"}},
+		},
+		"NextOffset": {
+			reason: "The next reminder should be sent once its offset is reached",
+			xr:     poll(`, "reminders": {"round": "100", "sent": ["30m"], "sentAt": 8200}`),
+			now:    closeTime - 300,
+			want:   want{reminded: []string{"U2", "U3"}, sent: []string{"30m", "5m"}},
+		},
+		"NewRound": {
+			reason: "Reminders sent in an earlier round should be sent again",
+			xr:     poll(`, "reminders": {"round": "50", "sent": ["30m"], "sentAt": 100}`),
+			now:    closeTime - 1800,
+			want:   want{reminded: []string{"U2", "U3"}, sent: []string{"30m"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv.Reset()
+			if err := TrackReminders(srv.Client(), "C1", tc.xr, tc.now, logging.NewNopLogger()); err != nil {
+				t.Fatalf("%s\nTrackReminders(...): %v", tc.reason, err)
+			}
+			// Running again in the same round sends nothing more.
+			if err := TrackReminders(srv.Client(), "C1", tc.xr, tc.now, logging.NewNopLogger()); err != nil {
+				t.Fatalf("%s\nTrackReminders(...): %v", tc.reason, err)
+			}
+
+			got := want{reminded: []string{}}
+			for _, m := range srv.Messages() {
+				got.reminded = append(got.reminded, m.Channel)
+			}
+			sort.Strings(got.reminded)
+			got.sent, _ = tc.xr.Resource.GetStringArray("status.reminders.sent")
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("%s\nTrackReminders(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package slackchannel
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/slack-go/slack"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// resultsEventType is the metadata event type of the results messages. Their
+// payload names the poll and the round they're the results of.
+const resultsEventType = "poll_results"
+
+// ResultsStatus records the results message of a round. It's recorded before
+// the results are posted, and completed with the timestamp of the message
+// once they are.
+type ResultsStatus struct {
+	Round       string `json:"round"`
+	Channel     string `json:"channel,omitempty"`
+	RequestedAt int64  `json:"requestedAt,omitempty"`
+	Timestamp   string `json:"ts,omitempty"`
+}
+
+// currentRound returns the ID of the current round of the poll. Polls opened
+// before rounds had IDs use the time they were notified.
+func currentRound(poll Poll) string {
+	if poll.Status.Round != "" {
+		return poll.Status.Round
+	}
+	return strconv.FormatInt(poll.Status.LastNotificationTime, 10)
+}
+
+// resultsMetadata returns the metadata of the results message of the round.
+func resultsMetadata(poll Poll, round string) slack.SlackMetadata {
+	return slack.SlackMetadata{
+		EventType:    resultsEventType,
+		EventPayload: map[string]interface{}{"poll": pollRef(poll), "round": round},
+	}
+}
+
+// findResults looks up the results message of the round among the messages
+// posted to the channel since the results were requested. It returns the
+// timestamp of the message, or an empty string if they weren't posted.
+func findResults(api Messenger, poll Poll, results ResultsStatus) (string, error) {
+	params := &slack.GetConversationHistoryParameters{
+		ChannelID:          results.Channel,
+		Oldest:             strconv.FormatInt(results.RequestedAt, 10),
+		Limit:              200,
+		IncludeAllMetadata: true,
+	}
+	for {
+		history, err := api.GetConversationHistory(params)
+		if err != nil {
+			return "", err
+		}
+		for _, m := range history.Messages {
+			payload := m.Metadata.EventPayload
+			if m.Metadata.EventType == resultsEventType && payload["poll"] == pollRef(poll) && payload["round"] == results.Round {
+				return m.Timestamp, nil
+			}
+		}
+		if history.ResponseMetaData.NextCursor == "" {
+			return "", nil
+		}
+		params.Cursor = history.ResponseMetaData.NextCursor
+	}
+}
+
+// setStatus writes the status of the poll back to the poll composite
+// resource. Only the status is written back, the spec belongs to the user.
+func setStatus(xr *resource.Composite, poll Poll) error {
+	status, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&poll.Status)
+	if err != nil {
+		return fmt.Errorf("cannot convert Poll status to Unstructured: %w", err)
+	}
+	if conditions, err := xr.Resource.GetValue("status.conditions"); err == nil {
+		status["conditions"] = conditions
+	}
+	return xr.Resource.SetValue("status", status)
+}
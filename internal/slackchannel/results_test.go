@@ -0,0 +1,67 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/slack-go/slack"
+
+	"github.com/crossplane/function-template-go/internal/slacktest"
+)
+
+func TestFindResults(t *testing.T) {
+	srv := slacktest.NewServer()
+	defer srv.Close()
+	api := srv.Client()
+
+	poll := Poll{}
+	poll.SetName("lunch")
+	other := Poll{}
+	other.SetName("dinner")
+	post := func(channel string, metadata slack.SlackMetadata) string {
+		t.Helper()
+		_, ts, err := api.PostMessage(channel, slack.MsgOptionText("results", false), slack.MsgOptionMetadata(metadata))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	post("C1", resultsMetadata(other, "50"))
+	post("C1", resultsMetadata(poll, "40"))
+	post("C2", resultsMetadata(poll, "50"))
+	ts := post("C1", resultsMetadata(poll, "50"))
+
+	cases := map[string]struct {
+		reason  string
+		results ResultsStatus
+		want    string
+	}{
+		"Posted": {
+			reason:  "The results message of the round should be found",
+			results: ResultsStatus{Round: "50", Channel: "C1"},
+			want:    ts,
+		},
+		"NotPosted": {
+			reason:  "Results of other rounds shouldn't be taken for the results of the round",
+			results: ResultsStatus{Round: "60", Channel: "C1"},
+			want:    "",
+		},
+		"PostedBefore": {
+			reason:  "Messages posted before the results were requested should be ignored",
+			results: ResultsStatus{Round: "50", Channel: "C1", RequestedAt: 1 << 40},
+			want:    "",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := findResults(api, poll, tc.results)
+			if err != nil {
+				t.Fatalf("%s\nfindResults(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nfindResults(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -2,35 +2,93 @@
 package slackchannel
 
 import (
-	"os"
+	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/crossplane/function-sdk-go/logging"
 	"github.com/crossplane/function-sdk-go/resource"
 	"github.com/crossplane/function-template-go/input/v1beta1"
+	"github.com/crossplane/function-template-go/internal/rankedchoice"
 )
 
-var (
-	channelId = os.Getenv("SLACK_CHANEL_ID")
-	pollTitle string
-)
+var pollTitle string
 
 // Voter represents the structure of an Voter reference.
 type Voter struct {
-	Name   string `json:"name"`
-	Status string `json:"status"`
+	Name    string            `json:"name"`
+	Status  string            `json:"status"`
+	Choices []string          `json:"choices,omitempty"`
+	Answer  string            `json:"answer,omitempty"`
+	Answers map[string]string `json:"answers,omitempty"`
+	Order   map[string]int64  `json:"order,omitempty"`
+}
+
+// choices returns every option the voter picked, in single or multiple selection mode.
+func (v Voter) choices() []string {
+	if len(v.Choices) > 0 {
+		return v.Choices
+	}
+	if v.Status != "" {
+		return []string{v.Status}
+	}
+	return nil
+}
+
+// voted checks if the voter has answered the poll in any way.
+func (v Voter) voted() bool {
+	return len(v.choices()) > 0 || v.Answer != "" || len(v.Answers) > 0 || len(v.Order) > 0
+}
+
+// Option represents a single answer a voter can choose.
+type Option struct {
+	Value       string `json:"value"`
+	Label       string `json:"label,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Answer represents the type of answer a poll expects: choice, text or number.
+// Min and Max bound number answers.
+type Answer struct {
+	Type string   `json:"type,omitempty"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+// Question represents one question of a multi-question poll.
+type Question struct {
+	ID      string   `json:"id"`
+	Prompt  string   `json:"prompt"`
+	Options []Option `json:"options,omitempty"`
+}
+
+// MenuItem represents an item voters can order in a meal poll.
+type MenuItem struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Currency string  `json:"currency,omitempty"`
 }
 
 // Message represents the structure of a message.
 type Message struct {
-	Question string `json:"question"`
-	Response string `json:"response"`
-	Result   string `json:"result"`
+	Question       string `json:"question"`
+	Response       string `json:"response"`
+	Result         string `json:"result"`
+	Delivery       string `json:"delivery,omitempty"`
+	PickupReminder string `json:"pickupReminder,omitempty"`
+}
+
+// ClaimRef references the PollClaim the poll was created for.
+type ClaimRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
 }
 
 // Poll represents the structure of a poll.
@@ -38,17 +96,49 @@ type Poll struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec              struct {
-		DeliveryTime int64   `json:"deliveryTime"`
-		DueOrderTime int64   `json:"dueOrderTime"`
-		DueTakeTime  int64   `json:"dueTakeTime"`
-		Schedule     string  `json:"schedule"`
-		Voters       []Voter `json:"voters"`
-		Title        string  `json:"title"`
-		Messages     Message `json:"messages"`
+		ClaimRef                  *ClaimRef          `json:"claimRef,omitempty"`
+		DeliveryTime              int64              `json:"deliveryTime"`
+		DueOrderTime              intstr.IntOrString `json:"dueOrderTime"`
+		CloseAt                   string             `json:"closeAt,omitempty"`
+		DueTakeTime               int64              `json:"dueTakeTime"`
+		Schedule                  string             `json:"schedule"`
+		Voters                    []Voter            `json:"voters"`
+		Title                     string             `json:"title"`
+		Messages                  Message            `json:"messages"`
+		Options                   []Option           `json:"options,omitempty"`
+		Selection                 string             `json:"selection,omitempty"`
+		Method                    string             `json:"method,omitempty"`
+		Answer                    Answer             `json:"answer,omitempty"`
+		Questions                 []Question         `json:"questions,omitempty"`
+		Menu                      []MenuItem         `json:"menu,omitempty"`
+		Payment                   *Payment           `json:"payment,omitempty"`
+		History                   History            `json:"history,omitempty"`
+		Calendar                  Calendar           `json:"calendar,omitempty"`
+		Reminders                 Reminders          `json:"reminders,omitempty"`
+		Organizers                []string           `json:"organizers,omitempty"`
+		Channel                   string             `json:"channel,omitempty"`
+		SlackCredentialsSecretRef *SecretKeyRef      `json:"slackCredentialsSecretRef,omitempty"`
 	} `json:"spec"`
 	Status struct {
-		Done                 bool  `json:"done"`
-		LastNotificationTime int64 `json:"lastNotificationTime"`
+		Voters               []Voter         `json:"voters"`
+		VotersMigrated       bool            `json:"votersMigrated,omitempty"`
+		Done                 bool            `json:"done"`
+		LastNotificationTime int64           `json:"lastNotificationTime"`
+		Phase                Phase           `json:"phase,omitempty"`
+		PhaseTimestamps      PhaseTimestamps `json:"phaseTimestamps,omitempty"`
+		Round                string          `json:"round,omitempty"`
+		CloseTime            int64           `json:"closeTime,omitempty"`
+		Extension            int64           `json:"extension,omitempty"`
+		OrderSheet           *OrderSheet     `json:"orderSheet,omitempty"`
+		Ledger               *Ledger         `json:"ledger,omitempty"`
+		Delivery             *Delivery       `json:"delivery,omitempty"`
+		Rounds               []Round         `json:"rounds,omitempty"`
+		ExcludedDates        []string        `json:"excludedDates,omitempty"`
+		SkippedRounds        []SkippedRound  `json:"skippedRounds,omitempty"`
+		NextRunTime          int64           `json:"nextRunTime,omitempty"`
+		Reminders            *ReminderStatus `json:"reminders,omitempty"`
+		Actions              []ActionRecord  `json:"actions,omitempty"`
+		Results              *ResultsStatus  `json:"results,omitempty"`
 	} `json:"status"`
 }
 
@@ -56,14 +146,14 @@ type Poll struct {
 func UserVoted(voters []Voter, userName string) bool {
 	for _, Voter := range voters {
 		if Voter.Name == userName {
-			return Voter.Status == ""
+			return !Voter.voted()
 		}
 	}
 	return true
 }
 
 // ProcessSlackMembers gets and process slack members
-func ProcessSlackMembers(api *slack.Client, channelID string, logger logging.Logger) ([]string, error) {
+func ProcessSlackMembers(api Messenger, channelID string, logger logging.Logger) ([]string, error) {
 	members, _, err := api.GetUsersInConversation(&slack.GetUsersInConversationParameters{ChannelID: channelID})
 	if err != nil {
 		return nil, err
@@ -83,51 +173,247 @@ func ProcessSlackMembers(api *slack.Client, channelID string, logger logging.Log
 	return realUsers, nil
 }
 
-func countUsers(voters []Voter) int {
+// slackUserIDs maps the names of the channel members to their Slack IDs.
+func slackUserIDs(api Messenger, channelID string) (map[string]string, error) {
+	members, _, err := api.GetUsersInConversation(&slack.GetUsersInConversationParameters{ChannelID: channelID})
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]string, len(members))
+	for _, memberID := range members {
+		userInfo, err := api.GetUserInfo(memberID)
+		if err != nil || userInfo.IsBot {
+			continue
+		}
+		ids[userInfo.Name] = userInfo.ID
+	}
+	return ids, nil
+}
+
+// defaultOptions are offered when a poll does not define its own options.
+var defaultOptions = []Option{{Value: "Yes"}, {Value: "No"}}
+
+// pollOptions returns the options of the poll, falling back to Yes/No.
+func pollOptions(options []Option) []Option {
+	if len(options) == 0 {
+		return defaultOptions
+	}
+	return options
+}
+
+// optionLabel returns the text shown to users for an option.
+func optionLabel(option Option) string {
+	if option.Label != "" {
+		return option.Label
+	}
+	return option.Value
+}
+
+// countVotes tallies the votes for every option, in option order. A voter
+// counts at most once per option, even in multiple selection mode.
+func countVotes(voters []Voter, options []Option) []int {
+	counts := make([]int, len(options))
+	for _, voter := range voters {
+		for i, option := range options {
+			for _, choice := range voter.choices() {
+				if strings.EqualFold(choice, option.Value) {
+					counts[i]++
+					break
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// formatTally renders the vote counts as one line per option.
+func formatTally(options []Option, counts []int) string {
+	lines := make([]string, 0, len(options))
+	for i, option := range options {
+		lines = append(lines, optionLabel(option)+": "+strconv.Itoa(counts[i]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatQuestions renders a tally per question of a multi-question poll.
+func formatQuestions(questions []Question, voters []Voter) string {
+	sections := make([]string, 0, len(questions))
+	for _, question := range questions {
+		answers := make([]Voter, 0, len(voters))
+		for _, voter := range voters {
+			answers = append(answers, Voter{Name: voter.Name, Status: voter.Answers[question.ID]})
+		}
+		options := pollOptions(question.Options)
+		sections = append(sections, "*"+question.Prompt+"*\n"+formatTally(options, countVotes(answers, options)))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// formatNumbers renders the sum of the numeric answers.
+func formatNumbers(voters []Voter) string {
+	sum := 0.0
 	count := 0
 	for _, voter := range voters {
-		if strings.EqualFold(voter.Status, "yes") {
-			count++
+		n, err := strconv.ParseFloat(voter.Answer, 64)
+		if err != nil {
+			continue
+		}
+		sum += n
+		count++
+	}
+	return "Total: " + strconv.FormatFloat(sum, 'f', -1, 64) + " (" + strconv.Itoa(count) + " answers)"
+}
+
+// formatTexts renders the text answers, one line per voter.
+func formatTexts(voters []Voter) string {
+	lines := make([]string, 0, len(voters))
+	for _, voter := range voters {
+		if voter.Answer != "" {
+			lines = append(lines, "• "+voter.Name+": "+voter.Answer)
 		}
 	}
-	return count
+	if len(lines) == 0 {
+		return "No answers"
+	}
+	return strings.Join(lines, "\n")
 }
 
-// SlackOrder sends an order notification via Slack.
-func SlackOrder(input *v1beta1.Input, api *slack.Client, xr *resource.Composite, logger logging.Logger, resultText string) *resource.Composite {
+// formatRankedChoice renders the winner of an instant-runoff count followed by
+// a summary of every round.
+func formatRankedChoice(options []Option, voters []Voter) string {
+	labels := make(map[string]string, len(options))
+	candidates := make([]string, 0, len(options))
+	for _, option := range options {
+		labels[option.Value] = optionLabel(option)
+		candidates = append(candidates, option.Value)
+	}
+	ballots := make([][]string, 0, len(voters))
+	for _, voter := range voters {
+		ballots = append(ballots, voter.choices())
+	}
+	result := rankedchoice.InstantRunoff(candidates, ballots)
+
+	winners := make([]string, 0, len(result.Winners))
+	for _, winner := range result.Winners {
+		winners = append(winners, labels[winner])
+	}
+	lines := make([]string, 0, len(result.Rounds)+1)
+	switch len(winners) {
+	case 0:
+		lines = append(lines, "No winner")
+	case 1:
+		lines = append(lines, "Winner: "+winners[0])
+	default:
+		lines = append(lines, "Tie: "+strings.Join(winners, ", "))
+	}
+	for i, round := range result.Rounds {
+		tallies := make([]string, 0, len(round.Tallies))
+		for _, tally := range round.Tallies {
+			tallies = append(tallies, labels[tally.Candidate]+" "+strconv.Itoa(tally.Votes))
+		}
+		line := "Round " + strconv.Itoa(i+1) + ": " + strings.Join(tallies, ", ")
+		if len(round.Eliminated) > 0 {
+			eliminated := make([]string, 0, len(round.Eliminated))
+			for _, candidate := range round.Eliminated {
+				eliminated = append(eliminated, labels[candidate])
+			}
+			line += " (eliminated " + strings.Join(eliminated, ", ") + ")"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SlackOrder sends an order notification via Slack and moves the poll from
+// Closed to ResultsPosted. The results are posted in two steps, so a retried
+// reconcile never posts them twice: the first records in status.results that
+// the results of the round are due, the next looks them up in the channel and
+// only posts them if they weren't posted since. If the results can't be
+// posted the poll stays Closed and the error is returned, posting is retried
+// on the next reconcile.
+func SlackOrder(input *v1beta1.Input, api Messenger, channelID string, xr *resource.Composite, logger logging.Logger, resultText string) error {
 	pollTitle, _ = xr.Resource.GetString("spec.title")
 
 	poll := Poll{}
 	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
-		logger.Info("error converting Unstructured to Poll:", err)
+		return fmt.Errorf("cannot convert composite to Poll: %w", err)
+	}
+	round := currentRound(poll)
+	results := poll.Status.Results
+	if results == nil || results.Round != round {
+		poll.Status.Results = &ResultsStatus{Round: round, Channel: channelID, RequestedAt: time.Now().Unix()}
+		return setStatus(xr, poll)
+	}
+
+	options := pollOptions(poll.Spec.Options)
+	var textContent string
+	attachments := []slack.Attachment{}
+	switch {
+	case len(poll.Spec.Menu) > 0:
+		sheet := buildOrderSheet(poll.Spec.Menu, poll.Status.Voters)
+		poll.Status.OrderSheet = &sheet
+		textContent = formatOrderSheet(sheet)
+		if poll.Spec.Payment != nil && len(sheet.People) > 0 {
+			ledger := buildLedger(*poll.Spec.Payment, sheet, time.Now().Unix())
+			poll.Status.Ledger = &ledger
+			attachments = append(attachments, paymentAttachment(pollRef(poll), ledger))
+		}
+	case len(poll.Spec.Questions) > 0:
+		textContent = formatQuestions(poll.Spec.Questions, poll.Status.Voters)
+	case poll.Spec.Answer.Type == "number":
+		textContent = formatNumbers(poll.Status.Voters)
+	case poll.Spec.Answer.Type == "text":
+		textContent = formatTexts(poll.Status.Voters)
+	case poll.Spec.Method == "rankedChoice":
+		textContent = formatRankedChoice(options, poll.Status.Voters)
+	default:
+		textContent = formatTally(options, countVotes(poll.Status.Voters, options))
 	}
-	textContent := countUsers(poll.Spec.Voters)
 
 	attachment := slack.Attachment{
 		Color:      "#f9a41b",
 		CallbackID: pollTitle,
 		Title:      pollTitle,
 		TitleLink:  pollTitle,
-		Text:       resultText + strconv.Itoa(textContent),
+		Text:       resultText + "\n" + textContent,
 		MarkdownIn: []string{},
 	}
 
-	channelID, timestamp, err := api.PostMessage(
-		channelId,
-		slack.MsgOptionText("", false),
-		slack.MsgOptionAttachments(attachment),
-		slack.MsgOptionAsUser(true),
-	)
+	if len(poll.Spec.Organizers) > 0 {
+		attachments = append(attachments, reopenAttachment(poll))
+	}
 
-	if err != nil {
-		logger.Info("error sending slack message", "warning", err)
-	} else {
-		logger.Info("message successfully sent to channel", channelID, timestamp)
+	if results.Channel == "" {
+		results.Channel = channelID
 	}
-	poll.Spec.Voters = nil
-	xr.Resource.Object, err = runtime.DefaultUnstructuredConverter.ToUnstructured(&poll)
-	if err != nil {
-		logger.Info("error converting Poll to Unstructured:", err)
+	if results.Timestamp == "" {
+		timestamp, err := findResults(api, poll, *results)
+		if err != nil {
+			return fmt.Errorf("cannot look up posted results: %w", err)
+		}
+		if timestamp == "" {
+			_, timestamp, err = api.PostMessage(
+				results.Channel,
+				slack.MsgOptionText("", false),
+				slack.MsgOptionAttachments(append([]slack.Attachment{attachment}, attachments...)...),
+				slack.MsgOptionMetadata(resultsMetadata(poll, round)),
+				slack.MsgOptionAsUser(true),
+			)
+			if err != nil {
+				return fmt.Errorf("cannot post results: %w", err)
+			}
+			logger.Info("message successfully sent to channel", results.Channel, timestamp)
+		}
+		results.Timestamp = timestamp
+	}
+	if err := poll.setPhase(PhaseResultsPosted, time.Now().Unix()); err != nil {
+		return err
+	}
+	poll.Status.Delivery = nil
+	if poll.Spec.DeliveryTime > 0 || poll.Spec.DueTakeTime > 0 {
+		poll.Status.Delivery = &Delivery{OrderedAt: time.Now().Unix(), Recipients: deliveryRecipients(poll)}
 	}
-	return xr
+	recordRound(&poll)
+	poll.Status.Voters = []Voter{}
+	return setStatus(xr, poll)
 }
@@ -0,0 +1,71 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCountVotes(t *testing.T) {
+	type args struct {
+		voters  []Voter
+		options []Option
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []int
+	}{
+		"DefaultOptions": {
+			reason: "Polls without options should be tallied as Yes/No",
+			args: args{
+				voters:  []Voter{{Name: "a", Status: "yes"}, {Name: "b", Status: "No"}, {Name: "c", Status: "Yes"}},
+				options: pollOptions(nil),
+			},
+			want: []int{2, 1},
+		},
+		"CustomOptions": {
+			reason: "Every configured option should be tallied, ignoring unknown values",
+			args: args{
+				voters:  []Voter{{Name: "a", Status: "pizza"}, {Name: "b", Status: "sushi"}, {Name: "c", Status: "pizza"}, {Name: "d", Status: "tacos"}, {Name: "e"}},
+				options: []Option{{Value: "pizza", Label: "Pizza"}, {Value: "sushi"}, {Value: "salad"}},
+			},
+			want: []int{2, 1, 0},
+		},
+		"MultipleSelection": {
+			reason: "Every choice of a voter should be tallied once",
+			args: args{
+				voters:  []Voter{{Name: "a", Choices: []string{"mon", "wed"}}, {Name: "b", Choices: []string{"wed", "wed"}}, {Name: "c", Status: "fri"}},
+				options: []Option{{Value: "mon"}, {Value: "wed"}, {Value: "fri"}},
+			},
+			want: []int{1, 2, 1},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := countVotes(tc.args.voters, tc.args.options)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\ncountVotes(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestFormatQuestions(t *testing.T) {
+	questions := []Question{
+		{ID: "mood", Prompt: "How was your week?", Options: []Option{{Value: "good", Label: "Good"}, {Value: "bad", Label: "Bad"}}},
+		{ID: "retro", Prompt: "Join the retro?"},
+	}
+	voters := []Voter{
+		{Name: "a", Answers: map[string]string{"mood": "good", "retro": "Yes"}},
+		{Name: "b", Answers: map[string]string{"mood": "bad"}},
+		{Name: "c", Answers: map[string]string{"mood": "good", "retro": "No"}},
+	}
+	want := "*How was your week?*\nGood: 2\nBad: 1\n\n*Join the retro?*\nYes: 1\nNo: 1"
+
+	if diff := cmp.Diff(want, formatQuestions(questions, voters)); diff != "" {
+		t.Errorf("formatQuestions(...): -want, +got:\n%s", diff)
+	}
+}
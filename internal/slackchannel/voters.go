@@ -0,0 +1,55 @@
+package slackchannel
+
+import (
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/function-sdk-go/logging"
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// mergeVoters adds the legacy voters to the voters in status, keeping the
+// status entry of voters present in both.
+func mergeVoters(legacy, voters []Voter) []Voter {
+	merged := make([]Voter, 0, len(legacy)+len(voters))
+	seen := make(map[string]bool, len(voters))
+	for _, voter := range voters {
+		seen[voter.Name] = true
+	}
+	for _, voter := range legacy {
+		if !seen[voter.Name] {
+			merged = append(merged, voter)
+		}
+	}
+	return append(merged, voters...)
+}
+
+// MigrateVoters moves the votes of polls created before votes were stored in
+// status from spec.voters to status.voters. It runs once per poll, afterwards
+// spec.voters is ignored and can be removed from the manifest.
+func MigrateVoters(xr *resource.Composite, logger logging.Logger) {
+	poll := Poll{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(xr.Resource.Object, &poll); err != nil {
+		logger.Info("error converting Unstructured to Poll:", err)
+		return
+	}
+	if poll.Status.VotersMigrated {
+		return
+	}
+	voters := mergeVoters(poll.Spec.Voters, poll.Status.Voters)
+	u := make([]interface{}, 0, len(voters))
+	for i := range voters {
+		v, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&voters[i])
+		if err != nil {
+			logger.Info("error converting Voter to Unstructured:", err)
+			return
+		}
+		u = append(u, v)
+	}
+	if err := xr.Resource.SetValue("status.voters", u); err != nil {
+		logger.Info("error setting voters status:", err)
+		return
+	}
+	if err := xr.Resource.SetBool("status.votersMigrated", true); err != nil {
+		logger.Info("error setting voters status:", err)
+	}
+}
@@ -0,0 +1,45 @@
+package slackchannel
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeVoters(t *testing.T) {
+	type args struct {
+		legacy []Voter
+		voters []Voter
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   []Voter
+	}{
+		"LegacyOnly": {
+			reason: "Votes from spec.voters should be moved to status",
+			args: args{
+				legacy: []Voter{{Name: "alice", Status: "Yes"}},
+			},
+			want: []Voter{{Name: "alice", Status: "Yes"}},
+		},
+		"StatusWins": {
+			reason: "A vote in status should replace the legacy vote of the same voter",
+			args: args{
+				legacy: []Voter{{Name: "alice", Status: "Yes"}, {Name: "bob", Status: "No"}},
+				voters: []Voter{{Name: "alice", Status: "No"}},
+			},
+			want: []Voter{{Name: "bob", Status: "No"}, {Name: "alice", Status: "No"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := mergeVoters(tc.args.legacy, tc.args.voters)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("%s\nmergeVoters(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+// Package slacktest serves a fake Slack Web API for tests. It records the
+// messages posted to it, serves them back as the history of the conversations
+// and serves configurable conversation members, so the poll can be exercised
+// without the real Slack.
+package slacktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Message is a message posted to the fake Slack.
+type Message struct {
+	Channel     string
+	User        string
+	Text        string
+	Attachments []slack.Attachment
+	Blocks      string
+	Metadata    slack.SlackMetadata
+	Ephemeral   bool
+	Timestamp   string
+}
+
+// Server is a fake Slack Web API.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	members  []slack.User
+	channels []slack.Channel
+	failures map[string]string
+	messages []Message
+}
+
+// NewServer starts a fake Slack Web API whose conversations have the given
+// members. Close it when done.
+func NewServer(members ...slack.User) *Server {
+	s := &Server{members: members, failures: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// User returns a channel member with the given ID and name.
+func User(id, name string) slack.User {
+	return slack.User{ID: id, Name: name}
+}
+
+// Bot returns a bot channel member with the given ID and name.
+func Bot(id, name string) slack.User {
+	return slack.User{ID: id, Name: name, IsBot: true}
+}
+
+// Channel returns a channel with the given ID and name.
+func Channel(id, name string) slack.Channel {
+	c := slack.Channel{}
+	c.ID = id
+	c.Name = name
+	return c
+}
+
+// APIURL returns the URL to point Slack clients to.
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// Client returns a Slack client of the fake Slack.
+func (s *Server) Client() *slack.Client {
+	return slack.New("xoxb-test", slack.OptionAPIURL(s.APIURL()))
+}
+
+// SetMembers replaces the members of the conversations.
+func (s *Server) SetMembers(members ...slack.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members = members
+}
+
+// SetChannels replaces the channels of the workspace.
+func (s *Server) SetChannels(channels ...slack.Channel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels = channels
+}
+
+// Fail makes the method fail with the given Slack error, like
+// "channel_not_found". An empty error makes it succeed again.
+func (s *Server) Fail(method, err string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == "" {
+		delete(s.failures, method)
+		return
+	}
+	s.failures[method] = err
+}
+
+// Messages returns the messages posted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// MessagesTo returns the messages posted to the channel or user.
+func (s *Server) MessagesTo(channel string) []Message {
+	messages := []Message{}
+	for _, m := range s.Messages() {
+		if m.Channel == channel {
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// Reset forgets the messages posted so far.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	method := r.URL.Path[1:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err, ok := s.failures[method]; ok {
+		reply(w, map[string]interface{}{"ok": false, "error": err})
+		return
+	}
+	switch method {
+	case "conversations.list":
+		reply(w, map[string]interface{}{"ok": true, "channels": s.channels, "response_metadata": map[string]string{"next_cursor": ""}})
+	case "conversations.members":
+		ids := make([]string, 0, len(s.members))
+		for _, member := range s.members {
+			ids = append(ids, member.ID)
+		}
+		reply(w, map[string]interface{}{"ok": true, "members": ids, "response_metadata": map[string]string{"next_cursor": ""}})
+	case "users.info":
+		for _, member := range s.members {
+			if member.ID == r.FormValue("user") {
+				reply(w, map[string]interface{}{"ok": true, "user": member})
+				return
+			}
+		}
+		reply(w, map[string]interface{}{"ok": false, "error": "user_not_found"})
+	case "conversations.history":
+		oldest, _ := strconv.ParseFloat(r.FormValue("oldest"), 64)
+		messages := []map[string]interface{}{}
+		// Slack returns the newest messages first.
+		for i := len(s.messages) - 1; i >= 0; i-- {
+			m := s.messages[i]
+			if ts, _ := strconv.ParseFloat(m.Timestamp, 64); m.Channel != r.FormValue("channel") || m.Ephemeral || ts < oldest {
+				continue
+			}
+			message := map[string]interface{}{"type": "message", "ts": m.Timestamp, "text": m.Text}
+			if r.FormValue("include_all_metadata") == "1" && m.Metadata.EventType != "" {
+				message["metadata"] = m.Metadata
+			}
+			messages = append(messages, message)
+		}
+		reply(w, map[string]interface{}{"ok": true, "messages": messages, "response_metadata": map[string]string{"next_cursor": ""}})
+	case "chat.postMessage", "chat.postEphemeral":
+		m := Message{
+			Channel:   r.FormValue("channel"),
+			User:      r.FormValue("user"),
+			Text:      r.FormValue("text"),
+			Blocks:    r.FormValue("blocks"),
+			Ephemeral: method == "chat.postEphemeral",
+			// Timestamps of messages are the Unix time they were posted at,
+			// unique within the channel.
+			Timestamp: fmt.Sprintf("%d.%06d", time.Now().Unix(), len(s.messages)+1),
+		}
+		if attachments := r.FormValue("attachments"); attachments != "" {
+			if err := json.Unmarshal([]byte(attachments), &m.Attachments); err != nil {
+				reply(w, map[string]interface{}{"ok": false, "error": "invalid_attachments"})
+				return
+			}
+		}
+		if metadata := r.FormValue("metadata"); metadata != "" {
+			if err := json.Unmarshal([]byte(metadata), &m.Metadata); err != nil {
+				reply(w, map[string]interface{}{"ok": false, "error": "invalid_metadata"})
+				return
+			}
+		}
+		s.messages = append(s.messages, m)
+		reply(w, map[string]interface{}{"ok": true, "channel": m.Channel, "ts": m.Timestamp, "message_ts": m.Timestamp})
+	default:
+		reply(w, map[string]interface{}{"ok": false, "error": "unknown_method"})
+	}
+}
+
+func reply(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -2,6 +2,10 @@
 package main
 
 import (
+	// Embed the IANA database so time zone validation does not depend on the
+	// zoneinfo of the image.
+	_ "time/tzdata"
+
 	"github.com/alecthomas/kong"
 
 	"github.com/crossplane/function-sdk-go"
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/crossplane/function-sdk-go/resource"
+)
+
+// statusFieldOwner is the field manager Crossplane applies the status the
+// function returns with.
+const statusFieldOwner = "apiextensions.crossplane.io/composite"
+
+// ownedStatusFields are the fields of the poll status only the function
+// writes. The desired composite holds these, and the parts of the shared
+// fields the function changed, so the fields other clients write aren't
+// applied back with the values the function observed.
+var ownedStatusFields = []string{
+	"reminders",
+	"actions",
+	"results",
+	"rounds",
+	"orderSheet",
+	"nextRunTime",
+	"votersMigrated",
+	"interactivityURL",
+	"channel",
+	"conditions",
+}
+
+// sharedStatusFields are the fields of the poll status the notifier or the
+// collector write too. Only the parts the function changed are returned,
+// and the parts it applied before that nobody changed since: Crossplane
+// removes the fields the function stops returning.
+var sharedStatusFields = []string{
+	"phase",
+	"phaseTimestamps",
+	"done",
+	"closeTime",
+	"extension",
+	"ledger",
+	"delivery",
+}
+
+// desiredStatus copies the status the function owns or changed from the poll
+// to the desired composite. The voters are recorded by the collector, they're
+// only copied when the function changed them, like when a round is reset.
+func desiredStatus(xr, observed, dxr *resource.Composite) error {
+	fields := ownedStatusFields
+	if !sameValue(xr, observed, "status.voters") {
+		fields = append(fields[:len(fields):len(fields)], "voters")
+	}
+	for _, field := range fields {
+		value, err := xr.Resource.GetValue("status." + field)
+		if err != nil {
+			continue
+		}
+		if err := dxr.Resource.SetValue("status."+field, value); err != nil {
+			return err
+		}
+	}
+	owned := appliedStatus(observed)
+	for _, field := range sharedStatusFields {
+		value, err := xr.Resource.GetValue("status." + field)
+		if err != nil {
+			continue
+		}
+		was, _ := observed.Resource.GetValue("status." + field)
+		fields, isOwned := owned["f:"+field].(map[string]interface{})
+		value, ok := changedOrApplied(value, was, fields, isOwned)
+		if !ok {
+			continue
+		}
+		if err := dxr.Resource.SetValue("status."+field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appliedStatus returns the status fields Crossplane applied for the function
+// last, as recorded by the managed fields of the poll.
+func appliedStatus(xr *resource.Composite) map[string]interface{} {
+	for _, m := range xr.Resource.GetManagedFields() {
+		if m.Manager != statusFieldOwner || m.Subresource != "status" || m.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(m.FieldsV1.Raw, &fields); err != nil {
+			return nil
+		}
+		status, _ := fields["f:status"].(map[string]interface{})
+		return status
+	}
+	return nil
+}
+
+// changedOrApplied returns the parts of the value that differ from the value
+// the function observed, or that the function applied before. Objects are
+// compared field by field, other values as a whole.
+func changedOrApplied(value, observed interface{}, applied map[string]interface{}, isApplied bool) (interface{}, bool) {
+	v, isObject := value.(map[string]interface{})
+	o, wasObject := observed.(map[string]interface{})
+	if !isObject || !wasObject {
+		return value, isApplied || !equalJSON(value, observed)
+	}
+	out := map[string]interface{}{}
+	for k := range v {
+		fields, isApplied := applied["f:"+k].(map[string]interface{})
+		if kept, ok := changedOrApplied(v[k], o[k], fields, isApplied); ok {
+			out[k] = kept
+		}
+	}
+	return out, len(out) > 0
+}
+
+// sameValue checks if the value at the path is the same in both composites.
+func sameValue(a, b *resource.Composite, path string) bool {
+	va, errA := a.Resource.GetValue(path)
+	vb, errB := b.Resource.GetValue(path)
+	if errA != nil || errB != nil {
+		return (errA != nil) == (errB != nil)
+	}
+	return equalJSON(va, vb)
+}
+
+// equalJSON checks if both values encode to the same JSON.
+func equalJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/function-sdk-go/resource"
+	"github.com/crossplane/function-sdk-go/resource/composite"
+)
+
+func TestDesiredStatus(t *testing.T) {
+	// poll returns a poll with the status, and the status fields Crossplane
+	// applied for the function last.
+	poll := func(status, applied string) *resource.Composite {
+		managed := ""
+		if applied != "" {
+			managed = `, "managedFields": [{"manager": "` + statusFieldOwner + `", "operation": "Apply", "subresource": "status", "fieldsType": "FieldsV1", "fieldsV1": {"f:status": ` + applied + `}}]`
+		}
+		xr := &resource.Composite{Resource: composite.New()}
+		if err := resource.AsObject(resource.MustStructJSON(`{
+			"apiVersion": "kndp.io/v1alpha1",
+			"kind": "Poll",
+			"metadata": {"name": "lunch"`+managed+`},
+			"spec": {"title": "lunch"},
+			"status": `+status+`
+		}`), xr.Resource); err != nil {
+			t.Fatal(err)
+		}
+		return xr
+	}
+
+	cases := map[string]struct {
+		reason   string
+		observed string
+		applied  string
+		xr       string
+		want     map[string]interface{}
+	}{
+		"Votes": {
+			reason:   "Votes the function didn't change shouldn't be returned, so votes recorded meanwhile aren't reverted",
+			observed: `{"phase": "Open", "round": "100", "voters": [{"name": "alice", "status": "Yes"}]}`,
+			xr:       `{"phase": "Open", "round": "100", "closeTime": 1000, "voters": [{"name": "alice", "status": "Yes"}]}`,
+			want:     map[string]interface{}{"closeTime": int64(1000)},
+		},
+		"RoundReset": {
+			reason:   "Votes reset by the function should be returned",
+			observed: `{"phase": "Closed", "round": "100", "voters": [{"name": "alice", "status": "Yes"}]}`,
+			xr:       `{"phase": "ResultsPosted", "round": "100", "voters": [], "rounds": [{"id": "100"}]}`,
+			want: map[string]interface{}{
+				"phase":  "ResultsPosted",
+				"voters": []interface{}{},
+				"rounds": []interface{}{map[string]interface{}{"id": "100"}},
+			},
+		},
+		"NotifierFields": {
+			reason:   "The excluded dates and skipped rounds only the notifier writes shouldn't be returned",
+			observed: `{"phase": "Pending", "excludedDates": ["2024-12-25"], "skippedRounds": [{"date": "2024-12-25"}]}`,
+			xr:       `{"phase": "Pending", "excludedDates": ["2024-12-25"], "skippedRounds": [{"date": "2024-12-25"}], "nextRunTime": 1000}`,
+			want:     map[string]interface{}{"nextRunTime": int64(1000)},
+		},
+		"PhaseOfOthers": {
+			reason:   "The phase the notifier wrote shouldn't be returned, so a round it opened meanwhile isn't reverted",
+			observed: `{"phase": "ResultsPosted", "done": true, "phaseTimestamps": {"open": 100, "resultsPosted": 200}}`,
+			xr:       `{"phase": "ResultsPosted", "done": true, "phaseTimestamps": {"open": 100, "resultsPosted": 200}}`,
+		},
+		"PhaseChanged": {
+			reason:   "Only the parts of the phase timestamps the function changed should be returned",
+			observed: `{"phase": "Open", "done": false, "phaseTimestamps": {"open": 100}}`,
+			xr:       `{"phase": "Closing", "done": true, "phaseTimestamps": {"open": 100, "closing": 200}}`,
+			want: map[string]interface{}{
+				"phase":           "Closing",
+				"done":            true,
+				"phaseTimestamps": map[string]interface{}{"closing": int64(200)},
+			},
+		},
+		"PhaseApplied": {
+			reason:   "The phase the function applied before should be returned, or Crossplane would remove it",
+			observed: `{"phase": "ResultsPosted", "done": true, "phaseTimestamps": {"open": 100, "resultsPosted": 200}}`,
+			applied:  `{"f:phase": {}, "f:done": {}, "f:phaseTimestamps": {".": {}, "f:resultsPosted": {}}}`,
+			xr:       `{"phase": "ResultsPosted", "done": true, "phaseTimestamps": {"open": 100, "resultsPosted": 200}}`,
+			want: map[string]interface{}{
+				"phase":           "ResultsPosted",
+				"done":            true,
+				"phaseTimestamps": map[string]interface{}{"resultsPosted": int64(200)},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dxr := &resource.Composite{Resource: composite.New()}
+			if err := desiredStatus(poll(tc.xr, ""), poll(tc.observed, tc.applied), dxr); err != nil {
+				t.Fatalf("%s\ndesiredStatus(...): %v", tc.reason, err)
+			}
+			want := map[string]interface{}{}
+			if tc.want != nil {
+				want["status"] = tc.want
+			}
+			if diff := cmp.Diff(want, dxr.Resource.Object); diff != "" {
+				t.Errorf("%s\ndesiredStatus(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}